@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go-auth/config"
+	"go-auth/pkg/database"
+	"go-auth/pkg/keyStore"
+	"log"
+	"os"
+	"time"
+)
+
+// Manages the JWT signing keys of a rotating key store.
+//
+//	go run ./cmd/keys <.env path> list
+//	go run ./cmd/keys <.env path> rotate
+//	go run ./cmd/keys <.env path> rotate -emergency
+//
+// An emergency rotation drops the active key at once, use it when the key has leaked.
+// Every token it signed stops verifying once the servers refresh their keys.
+func main() {
+	if len(os.Args) < 3 {
+		log.Fatal("Usage: keys <.env path> list|rotate [-emergency]")
+	}
+
+	ctx := context.Background()
+	cfg := config.LoadConfig(os.Args[1])
+
+	if cfg.Jwt.KeyStoreDriver == "" {
+		log.Fatal("Error: JWT_KEY_STORE_DRIVER is not set, keys are not rotated")
+	}
+
+	db := database.DbConn(ctx, cfg)
+	defer db.Disconnect(ctx)
+
+	// Open without rotating what is due, or a rotate would move the keys two steps
+	store, err := keyStore.OpenFromConfig(cfg, db)
+	if err != nil {
+		log.Fatalf("Error: Load signing keys failed: %s", err.Error())
+	}
+
+	switch os.Args[2] {
+	case "list":
+	case "rotate":
+		flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+		emergency := flags.Bool("emergency", false, "drop the active key instead of retiring it")
+		flags.Parse(os.Args[3:])
+
+		if err := store.Rotate(*emergency); err != nil {
+			log.Fatalf("Error: Rotate signing keys failed: %s", err.Error())
+		}
+		log.Println("Signing keys rotated")
+	default:
+		log.Fatalf("Error: Unknown command %s", os.Args[2])
+	}
+
+	for _, record := range store.Records() {
+		retireAt := ""
+		if record.RetireAt != nil {
+			retireAt = record.RetireAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%-8s %-6s %s %s\n", record.State, record.Alg, record.Kid, retireAt)
+	}
+}
@@ -3,12 +3,13 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
 
 	"go-auth/utils"
 
 	"github.com/joho/godotenv"
-	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/facebook"
+	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
 )
 
@@ -18,13 +19,26 @@ type (
 		*Db
 		*Jwt
 		*Grpc
-		Facebook *oauth2.Config
-		Google   *oauth2.Config
+		*SocialLogin
 		*Redis
+		*Mail
+		*PasswordReset
+		*EmailVerification
+		*Mfa
+		*WebAuthn
+		*Oidc
+		*LoginThrottle
+		*RateLimit
+		*PasswordHash
+		*PasswordPolicy
+		*TokenVault
 	}
 
 	Server struct {
 		Port int64
+		// TrustedProxies are the CIDRs of the proxies in front of the service. Only then is
+		// the client IP taken from X-Forwarded-For, otherwise it is the peer address.
+		TrustedProxies []string
 	}
 
 	Db struct {
@@ -38,6 +52,20 @@ type (
 		AccessTokenDuration  int64
 		RefreshTokenDuration int64
 		ApiDuration          int64
+		SigningAlg           string
+		PrivateKeyPath       string
+		KeyId                string
+		KeyStoreDriver       string
+		KeyStorePath         string
+		KeyRotationInterval  int64
+		KeyRefreshInterval   int64
+		// ServiceTokenDuration is in minutes
+		ServiceTokenDuration int64
+		// ServiceAccountSecretGracePeriod is how many minutes a rotated secret keeps working
+		ServiceAccountSecretGracePeriod int64
+		RevocationFilterCapacity        int64
+		// RevocationFilterRefreshInterval is in seconds
+		RevocationFilterRefreshInterval int64
 	}
 
 	Redis struct {
@@ -46,6 +74,135 @@ type (
 		DB       int
 	}
 
+	Mail struct {
+		Driver       string
+		From         string
+		FilePath     string
+		SmtpHost     string
+		SmtpPort     int64
+		SmtpUsername string
+		SmtpPassword string
+	}
+
+	PasswordReset struct {
+		Url      string
+		Duration int64
+	}
+
+	EmailVerification struct {
+		Url            string
+		Duration       int64
+		ResendInterval int64
+		Required       bool
+		RequiredRoles  []string
+	}
+
+	Mfa struct {
+		Issuer            string
+		ChallengeDuration int64
+		MaxAttempts       int64
+	}
+
+	WebAuthn struct {
+		RPID          string
+		RPDisplayName string
+		RPOrigins     []string
+	}
+
+	Oidc struct {
+		Issuer   string
+		ClientId string
+		// AuthorizationEndpoint is the page of the login UI that clients send the browser
+		// to. It signs the user in, calls GET /oauth/authorize with their access token and
+		// follows the redirect_to of the answer.
+		AuthorizationEndpoint string
+		IdTokenDuration       int64
+		// AuthorizationCodeDuration is in seconds
+		AuthorizationCodeDuration int64
+	}
+
+	LoginThrottle struct {
+		MaxAccountFailures int64
+		MaxIpFailures      int64
+		// FailureWindow is how many minutes a failed attempt counts towards a lockout
+		FailureWindow int64
+		// LockoutDuration is in minutes
+		LockoutDuration int64
+		// DelayBase and MaxDelay are in milliseconds. The delay doubles with every failure.
+		DelayBase int64
+		MaxDelay  int64
+	}
+
+	RateLimit struct {
+		// Driver is "redis" or "memory", which counts per instance
+		Driver string
+	}
+
+	PasswordHash struct {
+		// Algorithm new passwords are hashed with, "argon2id" or "bcrypt"
+		Algorithm string
+		// Argon2Memory is in KiB
+		Argon2Memory      int64
+		Argon2Iterations  int64
+		Argon2Parallelism int64
+		BcryptCost        int64
+	}
+
+	PasswordPolicy struct {
+		MinLength     int64
+		MaxLength     int64
+		RequireUpper  bool
+		RequireLower  bool
+		RequireDigit  bool
+		RequireSymbol bool
+		// HistorySize is how many of the latest passwords may not be reused, 0 allows any
+		HistorySize int64
+		// BreachedPasswordsPath is a file or a directory of SHA-1 hashes in the format of
+		// Have I Been Pwned. Empty turns the check off.
+		BreachedPasswordsPath string
+	}
+
+	// TokenVault encrypts the tokens social login providers issue to our users
+	TokenVault struct {
+		// Keys are id:key pairs with base64 AES keys. All of them decrypt, the active one
+		// encrypts, so a new key can be rolled in before the old one is dropped.
+		Keys        []string
+		ActiveKeyId string
+	}
+
+	SocialLogin struct {
+		// StateDuration is how many seconds a user has to come back from the provider
+		StateDuration int64
+		Providers     []*SocialProvider
+	}
+
+	// SocialProvider is a provider users can sign in with. Setting the issuer makes it an
+	// OpenID Connect provider, whose endpoints are found by discovery and whose ID tokens
+	// are verified. Without one it is plain OAuth 2.0 and the identity comes from the
+	// user info url.
+	SocialProvider struct {
+		Name         string
+		Issuer       string
+		AuthUrl      string
+		TokenUrl     string
+		UserInfoUrl  string
+		JwksUrl      string
+		ClientId     string
+		ClientSecret string
+		RedirectUrl  string
+		Scopes       []string
+		// ResponseMode "form_post" has the provider post the callback instead of
+		// redirecting to it
+		ResponseMode string
+		// ClaimMapping names the claim of the provider behind subject, email,
+		// email_verified, name and picture, where it differs from those names
+		ClaimMapping map[string]string
+		// Apple wants a client secret signed with a key of the team instead of a fixed one
+		TeamId         string
+		KeyId          string
+		PrivateKeyPath string
+	}
+
 	Grpc struct {
 		AuthUrl string
 		UserUrl string
@@ -59,32 +216,231 @@ func LoadConfig(path string) *Config {
 
 	return &Config{
 		Server: &Server{
-			Port: utils.ParseStringToInt(os.Getenv("SERVER_PORT")),
+			Port:           utils.ParseStringToInt(os.Getenv("SERVER_PORT")),
+			TrustedProxies: utils.ParseStringToSlice(os.Getenv("TRUSTED_PROXIES")),
 		},
 		Db: &Db{
 			URI: os.Getenv("DB_URI"),
 		},
 		Jwt: &Jwt{
-			AccessTokenSecret:    os.Getenv("ACCESS_TOKEN_SECRET"),
-			RefreshTokenSecret:   os.Getenv("REFRESH_TOKEN_SECRET"),
-			ApiSecret:            os.Getenv("API_SECRET"),
-			AccessTokenDuration:  utils.ParseStringToInt(os.Getenv("ACCESS_TOKEN_DURATION")),
-			RefreshTokenDuration: utils.ParseStringToInt(os.Getenv("REFRESH_TOKEN_DURATION")),
-			ApiDuration:          utils.ParseStringToInt(os.Getenv("ACCESS_TOKEN_DURATION")),
-		},
-		Facebook: &oauth2.Config{
-			ClientID:     os.Getenv("OAUTH2_FACEBOOK_CLIENT_ID"),
-			ClientSecret: os.Getenv("OAUTH2_FACEBOOK_CLIENT_SECRET"),
-			RedirectURL:  os.Getenv("OAUTH2_FACEBOOK_REDIRECT_URL"),
-			Endpoint:     facebook.Endpoint,
-			Scopes:       []string{"email"},
-		},
-		Google: &oauth2.Config{
-			ClientID:     os.Getenv("OAUTH2_GOOGLE_CLIENT_ID"),
-			ClientSecret: os.Getenv("OAUTH2_GOOGLE_CLIENT_SECRET"),
-			RedirectURL:  os.Getenv("OAUTH2_GOOGLE_REDIRECT_URL"),
-			Endpoint:     google.Endpoint,
-			Scopes:       []string{"email"},
+			AccessTokenSecret:               os.Getenv("ACCESS_TOKEN_SECRET"),
+			RefreshTokenSecret:              os.Getenv("REFRESH_TOKEN_SECRET"),
+			ApiSecret:                       os.Getenv("API_SECRET"),
+			AccessTokenDuration:             utils.ParseStringToInt(os.Getenv("ACCESS_TOKEN_DURATION")),
+			RefreshTokenDuration:            utils.ParseStringToInt(os.Getenv("REFRESH_TOKEN_DURATION")),
+			ApiDuration:                     utils.ParseStringToInt(os.Getenv("ACCESS_TOKEN_DURATION")),
+			SigningAlg:                      os.Getenv("JWT_SIGNING_ALG"),
+			PrivateKeyPath:                  os.Getenv("JWT_PRIVATE_KEY_PATH"),
+			KeyId:                           os.Getenv("JWT_KEY_ID"),
+			KeyStoreDriver:                  os.Getenv("JWT_KEY_STORE_DRIVER"),
+			KeyStorePath:                    os.Getenv("JWT_KEY_STORE_PATH"),
+			KeyRotationInterval:             utils.ParseStringToIntOrDefault(os.Getenv("JWT_KEY_ROTATION_INTERVAL"), 720),
+			KeyRefreshInterval:              utils.ParseStringToIntOrDefault(os.Getenv("JWT_KEY_REFRESH_INTERVAL"), 60),
+			ServiceTokenDuration:            utils.ParseStringToIntOrDefault(os.Getenv("SERVICE_TOKEN_DURATION"), 15),
+			ServiceAccountSecretGracePeriod: utils.ParseStringToIntOrDefault(os.Getenv("SERVICE_ACCOUNT_SECRET_GRACE_PERIOD"), 60),
+			RevocationFilterCapacity:        utils.ParseStringToIntOrDefault(os.Getenv("REVOCATION_FILTER_CAPACITY"), 1000000),
+			RevocationFilterRefreshInterval: utils.ParseStringToIntOrDefault(os.Getenv("REVOCATION_FILTER_REFRESH_INTERVAL"), 300),
+		},
+		SocialLogin: &SocialLogin{
+			StateDuration: utils.ParseStringToIntOrDefault(os.Getenv("SOCIAL_LOGIN_STATE_DURATION"), 600),
+			Providers:     loadSocialProviders(),
+		},
+		Redis: &Redis{
+			Address:  os.Getenv("REDIS_ADDRESS"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       int(utils.ParseStringToInt(os.Getenv("REDIS_DB"))),
+		},
+		Mail: &Mail{
+			Driver:       os.Getenv("MAIL_DRIVER"),
+			From:         os.Getenv("MAIL_FROM"),
+			FilePath:     os.Getenv("MAIL_FILE_PATH"),
+			SmtpHost:     os.Getenv("MAIL_SMTP_HOST"),
+			SmtpPort:     utils.ParseStringToIntOrDefault(os.Getenv("MAIL_SMTP_PORT"), 587),
+			SmtpUsername: os.Getenv("MAIL_SMTP_USERNAME"),
+			SmtpPassword: os.Getenv("MAIL_SMTP_PASSWORD"),
+		},
+		PasswordReset: &PasswordReset{
+			Url:      os.Getenv("PASSWORD_RESET_URL"),
+			Duration: utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_RESET_DURATION"), 30),
 		},
+		EmailVerification: &EmailVerification{
+			Url:            os.Getenv("EMAIL_VERIFICATION_URL"),
+			Duration:       utils.ParseStringToIntOrDefault(os.Getenv("EMAIL_VERIFICATION_DURATION"), 24),
+			ResendInterval: utils.ParseStringToIntOrDefault(os.Getenv("EMAIL_VERIFICATION_RESEND_INTERVAL"), 60),
+			Required:       os.Getenv("EMAIL_VERIFICATION_REQUIRED") == "true",
+			RequiredRoles:  utils.ParseStringToSlice(os.Getenv("EMAIL_VERIFICATION_REQUIRED_ROLES")),
+		},
+		Mfa: &Mfa{
+			Issuer:            os.Getenv("MFA_ISSUER"),
+			ChallengeDuration: utils.ParseStringToIntOrDefault(os.Getenv("MFA_CHALLENGE_DURATION"), 5),
+			MaxAttempts:       utils.ParseStringToIntOrDefault(os.Getenv("MFA_MAX_ATTEMPTS"), 5),
+		},
+		WebAuthn: &WebAuthn{
+			RPID:          os.Getenv("WEBAUTHN_RP_ID"),
+			RPDisplayName: os.Getenv("WEBAUTHN_RP_DISPLAY_NAME"),
+			RPOrigins:     utils.ParseStringToSlice(os.Getenv("WEBAUTHN_RP_ORIGINS")),
+		},
+		Oidc: &Oidc{
+			Issuer:                    os.Getenv("JWT_ISSUER"),
+			ClientId:                  os.Getenv("OIDC_CLIENT_ID"),
+			AuthorizationEndpoint:     os.Getenv("OIDC_AUTHORIZATION_ENDPOINT"),
+			IdTokenDuration:           utils.ParseStringToIntOrDefault(os.Getenv("OIDC_ID_TOKEN_DURATION"), 60),
+			AuthorizationCodeDuration: utils.ParseStringToIntOrDefault(os.Getenv("OAUTH_AUTHORIZATION_CODE_DURATION"), 60),
+		},
+		LoginThrottle: &LoginThrottle{
+			MaxAccountFailures: utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_MAX_ACCOUNT_FAILURES"), 5),
+			MaxIpFailures:      utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_MAX_IP_FAILURES"), 20),
+			FailureWindow:      utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_FAILURE_WINDOW"), 15),
+			LockoutDuration:    utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_LOCKOUT_DURATION"), 15),
+			DelayBase:          utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_DELAY_BASE"), 500),
+			MaxDelay:           utils.ParseStringToIntOrDefault(os.Getenv("LOGIN_MAX_DELAY"), 30000),
+		},
+		RateLimit: &RateLimit{
+			Driver: os.Getenv("RATE_LIMIT_DRIVER"),
+		},
+		PasswordHash: &PasswordHash{
+			Algorithm:         os.Getenv("PASSWORD_HASH_ALGORITHM"),
+			Argon2Memory:      utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_HASH_ARGON2_MEMORY"), 65536),
+			Argon2Iterations:  utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_HASH_ARGON2_ITERATIONS"), 3),
+			Argon2Parallelism: utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_HASH_ARGON2_PARALLELISM"), 2),
+			BcryptCost:        utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_HASH_BCRYPT_COST"), 10),
+		},
+		PasswordPolicy: &PasswordPolicy{
+			MinLength:             utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_POLICY_MIN_LENGTH"), 8),
+			MaxLength:             utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_POLICY_MAX_LENGTH"), 128),
+			RequireUpper:          os.Getenv("PASSWORD_POLICY_REQUIRE_UPPER") == "true",
+			RequireLower:          os.Getenv("PASSWORD_POLICY_REQUIRE_LOWER") == "true",
+			RequireDigit:          os.Getenv("PASSWORD_POLICY_REQUIRE_DIGIT") == "true",
+			RequireSymbol:         os.Getenv("PASSWORD_POLICY_REQUIRE_SYMBOL") == "true",
+			HistorySize:           utils.ParseStringToIntOrDefault(os.Getenv("PASSWORD_POLICY_HISTORY_SIZE"), 5),
+			BreachedPasswordsPath: os.Getenv("PASSWORD_POLICY_BREACHED_PASSWORDS_PATH"),
+		},
+		TokenVault: &TokenVault{
+			Keys:        utils.ParseStringToSlice(os.Getenv("TOKEN_VAULT_KEYS")),
+			ActiveKeyId: os.Getenv("TOKEN_VAULT_ACTIVE_KEY_ID"),
+		},
+	}
+}
+
+// Settings of the well known providers, the environment only needs their credentials
+var socialProviderDefaults = map[string]SocialProvider{
+	// The endpoints are known, so Google needs no discovery
+	"google": {
+		Issuer:      "https://accounts.google.com",
+		AuthUrl:     google.Endpoint.AuthURL,
+		TokenUrl:    google.Endpoint.TokenURL,
+		UserInfoUrl: "https://openidconnect.googleapis.com/v1/userinfo",
+		JwksUrl:     "https://www.googleapis.com/oauth2/v3/certs",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	// GitHub has no OpenID Connect, and the email of /user is only the public one
+	"github": {
+		AuthUrl:      github.Endpoint.AuthURL,
+		TokenUrl:     github.Endpoint.TokenURL,
+		UserInfoUrl:  "https://api.github.com/user",
+		Scopes:       []string{"read:user", "user:email"},
+		ClaimMapping: map[string]string{"subject": "id", "picture": "avatar_url"},
+	},
+	// Apple only shares the email when it may post the callback
+	"apple": {
+		Issuer:       "https://appleid.apple.com",
+		AuthUrl:      "https://appleid.apple.com/auth/authorize",
+		TokenUrl:     "https://appleid.apple.com/auth/token",
+		JwksUrl:      "https://appleid.apple.com/auth/keys",
+		Scopes:       []string{"name", "email"},
+		ResponseMode: "form_post",
+	},
+	"facebook": {
+		AuthUrl:      facebook.Endpoint.AuthURL,
+		TokenUrl:     facebook.Endpoint.TokenURL,
+		UserInfoUrl:  "https://graph.facebook.com/me?fields=id,name,email,picture",
+		Scopes:       []string{"public_profile", "email"},
+		ClaimMapping: map[string]string{"subject": "id", "picture": "picture.data.url"},
+	},
+}
+
+// loadSocialProviders reads the providers named in OAUTH2_PROVIDERS from the
+// OAUTH2_<NAME>_* settings. Providers without a client id are left out.
+func loadSocialProviders() []*SocialProvider {
+	names := utils.ParseStringToSlice(os.Getenv("OAUTH2_PROVIDERS"))
+	if os.Getenv("OAUTH2_PROVIDERS") == "" {
+		names = []string{"facebook", "google"}
+	}
+
+	providers := make([]*SocialProvider, 0)
+	for _, name := range names {
+		name = strings.ToLower(name)
+		prefix := "OAUTH2_" + strings.ToUpper(name) + "_"
+
+		provider := socialProviderDefaults[name]
+		provider.Name = name
+		provider.ClientId = os.Getenv(prefix + "CLIENT_ID")
+		provider.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+		provider.RedirectUrl = os.Getenv(prefix + "REDIRECT_URL")
+
+		if provider.ClientId == "" {
+			continue
+		}
+
+		if v := os.Getenv(prefix + "ISSUER"); v != "" {
+			provider.Issuer = v
+		}
+		if v := os.Getenv(prefix + "AUTH_URL"); v != "" {
+			provider.AuthUrl = v
+		}
+		if v := os.Getenv(prefix + "TOKEN_URL"); v != "" {
+			provider.TokenUrl = v
+		}
+		if v := os.Getenv(prefix + "USERINFO_URL"); v != "" {
+			provider.UserInfoUrl = v
+		}
+		if v := os.Getenv(prefix + "JWKS_URL"); v != "" {
+			provider.JwksUrl = v
+		}
+		if v := utils.ParseStringToSlice(os.Getenv(prefix + "SCOPES")); len(v) > 0 {
+			provider.Scopes = v
+		}
+		if v := os.Getenv(prefix + "RESPONSE_MODE"); v != "" {
+			provider.ResponseMode = v
+		}
+		provider.TeamId = os.Getenv(prefix + "TEAM_ID")
+		provider.KeyId = os.Getenv(prefix + "KEY_ID")
+		provider.PrivateKeyPath = os.Getenv(prefix + "PRIVATE_KEY_PATH")
+
+		// Given as field=claim pairs, such as "subject=id,picture=avatar_url"
+		mapping := make(map[string]string)
+		for field, claim := range provider.ClaimMapping {
+			mapping[field] = claim
+		}
+		for _, pair := range utils.ParseStringToSlice(os.Getenv(prefix + "CLAIM_MAPPING")) {
+			if field, claim, ok := strings.Cut(pair, "="); ok {
+				mapping[strings.TrimSpace(field)] = strings.TrimSpace(claim)
+			}
+		}
+		provider.ClaimMapping = mapping
+
+		providers = append(providers, &provider)
 	}
+
+	return providers
+}
+
+// RequiredFor reports whether an account with the role must verify its email before login.
+// When no roles are configured the policy applies to every role.
+func (e *EmailVerification) RequiredFor(role string) bool {
+	if !e.Required {
+		return false
+	}
+
+	if len(e.RequiredRoles) == 0 {
+		return true
+	}
+
+	for _, r := range e.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// ApiKeyAuthenticator returns the claims of the owner of an API key
+type ApiKeyAuthenticator func(c echo.Context, key string) (*jwtAuth.AuthMapClaims, error)
+
+// JWTOrApiKeyMiddleware accepts a personal API key, in the X-API-Key header or as a bearer
+// token, next to the usual access token. Either way handlers find a *jwt.Token with
+// *jwtAuth.AuthMapClaims under "user". audiences are passed on to JWTMiddleware.
+func JWTOrApiKeyMiddleware(authenticate ApiKeyAuthenticator, audiences ...string) echo.MiddlewareFunc {
+	jwtMiddleware := JWTMiddleware(audiences...)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJwt := jwtMiddleware(next)
+
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get("X-API-Key")
+			if key == "" {
+				bearer := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+				if strings.HasPrefix(bearer, jwtAuth.ApiKeyPrefix) {
+					key = bearer
+				}
+			}
+
+			if key == "" {
+				return withJwt(c)
+			}
+
+			claims, err := authenticate(c, key)
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid API key"})
+			}
+
+			c.Set("user", &jwt.Token{Claims: claims, Valid: true})
+
+			return next(c)
+		}
+	}
+}
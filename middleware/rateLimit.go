@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/rateLimit"
+	"go-auth/pkg/secureToken"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	// RateLimitKeyFunc returns who a request is counted against
+	RateLimitKeyFunc func(c echo.Context) string
+
+	RateLimitConfig struct {
+		// Name keeps the counts of routes sharing a key func apart
+		Name    string
+		Limit   int64
+		Window  time.Duration
+		KeyFunc RateLimitKeyFunc
+	}
+)
+
+func rateLimitClaims(c echo.Context) *jwtAuth.AuthMapClaims {
+	userJwt, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil
+	}
+
+	claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
+	if !ok || claims.Claims == nil {
+		return nil
+	}
+
+	return claims
+}
+
+func KeyByIp(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// KeyByUserId has to run after JWTMiddleware or JWTOrApiKeyMiddleware. Anonymous
+// requests are counted by IP.
+func KeyByUserId(c echo.Context) string {
+	if claims := rateLimitClaims(c); claims != nil && claims.UserId != "" {
+		return "user:" + claims.UserId
+	}
+	return KeyByIp(c)
+}
+
+// KeyByClientId counts the client of a verified access token, so it has to run after
+// JWTMiddleware. Anything else is counted by IP: a client_id that has not been checked
+// yet could be anyone's, and would let a caller spend the limit of another client.
+func KeyByClientId(c echo.Context) string {
+	if claims := rateLimitClaims(c); claims != nil && claims.ClientId != "" {
+		return "client:" + claims.ClientId
+	}
+
+	return KeyByIp(c)
+}
+
+// KeyByApiKey counts a personal API key by its hash, so the key never reaches Redis.
+// Requests with an access token instead are counted like KeyByUserId, so it has to run
+// after JWTOrApiKeyMiddleware.
+func KeyByApiKey(c echo.Context) string {
+	key := c.Request().Header.Get("X-API-Key")
+	if key == "" {
+		bearer := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if strings.HasPrefix(bearer, jwtAuth.ApiKeyPrefix) {
+			key = bearer
+		}
+	}
+
+	if key != "" {
+		return "api_key:" + secureToken.HashToken(key)
+	}
+	return KeyByUserId(c)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimit turns requests away with 429 once the key used up its limit for the window.
+// Every response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset
+// headers, and a turned away one also Retry-After. The limit is not enforced while the
+// limiter is down, an outage must not take every route with it.
+func RateLimit(limiter rateLimit.Limiter, config RateLimitConfig) echo.MiddlewareFunc {
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIp
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
+			defer cancel()
+
+			result, err := limiter.Allow(ctx, config.Name+":"+keyFunc(c), config.Limit, config.Window)
+			if err != nil {
+				log.Printf("Error: Check rate limit failed: %s", err.Error())
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+			header.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.Reset))
+				return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Too many requests, try again later"})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireRole only lets users with one of the roles through. It has to run after
+// JWTMiddleware. Tokens issued to third party clients never pass, whatever the role of
+// the user behind them.
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userJwt, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
+			if !ok || claims.Claims == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			if claims.ClientId == "" {
+				for _, role := range roles {
+					if claims.RoleCode == role {
+						return next(c)
+					}
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden"})
+		}
+	}
+}
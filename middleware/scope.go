@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireScope only lets tokens granted the scope through. It has to run after
+// JWTMiddleware or JWTOrApiKeyMiddleware. Access tokens users got from the service itself
+// act with every right of the user and always pass, API keys and the tokens of
+// third-party clients only carry the scopes they were given.
+func RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userJwt, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
+			if !ok || claims.Claims == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			if claims.Subject == "access-token" && claims.ClientId == "" {
+				return next(c)
+			}
+
+			for _, granted := range strings.Fields(claims.Scope) {
+				if granted == scope {
+					return next(c)
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden"})
+		}
+	}
+}
+
+// RequireServiceScope only lets service accounts with the scope through. It has to run
+// after JWTMiddleware. Tokens of users never pass, they act for themselves and not as a
+// trusted service.
+func RequireServiceScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userJwt, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
+			if !ok || claims.Claims == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "JWT token missing or invalid"})
+			}
+
+			if claims.Subject == "service-token" && claims.UserId == "" && claims.ClientId != "" {
+				for _, granted := range strings.Fields(claims.Scope) {
+					if granted == scope {
+						return next(c)
+					}
+				}
+			}
+
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden"})
+		}
+	}
+}
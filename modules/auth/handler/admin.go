@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) UpdateUserRole(c echo.Context) error {
+	var updateReq model.UpdateRoleReq
+	if err := c.Bind(&updateReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(updateReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.FormatValidationError(err))
+	}
+
+	if err := h.authUsecase.UpdateUserRole(c.Param("id"), &updateReq); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Role updated"})
+}
+
+func (h *authHandler) UnlockUser(c echo.Context) error {
+	if err := h.authUsecase.UnlockUser(c, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User unlocked"})
+}
+
+func (h *authHandler) DisableUser(c echo.Context) error {
+	if err := h.authUsecase.DisableUser(c, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User disabled"})
+}
+
+func (h *authHandler) EnableUser(c echo.Context) error {
+	if err := h.authUsecase.EnableUser(c, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrUserNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "User enabled"})
+}
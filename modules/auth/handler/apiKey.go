@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) CreateApiKey(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	var createReq model.CreateApiKeyReq
+	if err := c.Bind(&createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.FormatValidationError(err))
+	}
+
+	apiKey, err := h.authUsecase.CreateApiKey(h.cfg, claims.UserId, &createReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, apiKey)
+}
+
+func (h *authHandler) ListApiKeys(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	apiKeys, err := h.authUsecase.ListApiKeys(claims.UserId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, apiKeys)
+}
+
+func (h *authHandler) RevokeApiKey(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.authUsecase.RevokeApiKey(claims.UserId, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrApiKeyNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "API key revoked"})
+}
@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"go-auth/config"
@@ -12,8 +10,10 @@ import (
 	"go-auth/pkg/jwtAuth"
 	"go-auth/utils"
 	"log"
+	"math"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
@@ -27,9 +27,52 @@ type (
 		Login(c echo.Context) error
 		Logout(c echo.Context) error
 		RefreshToken(c echo.Context) error
-		FacebookLogin(c echo.Context) error
-		FacebookCallback(c echo.Context) error
+		SocialLogin(c echo.Context) error
+		SocialCallback(c echo.Context) error
+		SocialIdTokenLogin(c echo.Context) error
+		LinkIdentity(c echo.Context) error
+		ListIdentities(c echo.Context) error
+		UnlinkIdentity(c echo.Context) error
+		ProviderToken(c echo.Context) error
 		FindUserByUID(c echo.Context) error
+		ListSessions(c echo.Context) error
+		RevokeSession(c echo.Context) error
+		RevokeOtherSessions(c echo.Context) error
+		ForgotPassword(c echo.Context) error
+		ResetPassword(c echo.Context) error
+		ChangePassword(c echo.Context) error
+		VerifyEmail(c echo.Context) error
+		ResendVerificationEmail(c echo.Context) error
+		LoginMfa(c echo.Context) error
+		EnrollTotp(c echo.Context) error
+		ConfirmTotp(c echo.Context) error
+		DisableMfa(c echo.Context) error
+		RegenerateRecoveryCodes(c echo.Context) error
+		BeginPasskeyRegistration(c echo.Context) error
+		FinishPasskeyRegistration(c echo.Context) error
+		BeginPasskeyLogin(c echo.Context) error
+		FinishPasskeyLogin(c echo.Context) error
+		JWKS(c echo.Context) error
+		OpenIdConfiguration(c echo.Context) error
+		UserInfo(c echo.Context) error
+		CreateOAuthClient(c echo.Context) error
+		ListOAuthClients(c echo.Context) error
+		DisableOAuthClient(c echo.Context) error
+		Authorize(c echo.Context) error
+		Token(c echo.Context) error
+		CreateServiceAccount(c echo.Context) error
+		ListServiceAccounts(c echo.Context) error
+		RotateServiceAccountSecret(c echo.Context) error
+		DisableServiceAccount(c echo.Context) error
+		CreateApiKey(c echo.Context) error
+		ListApiKeys(c echo.Context) error
+		RevokeApiKey(c echo.Context) error
+		Introspect(c echo.Context) error
+		RevokeToken(c echo.Context) error
+		UpdateUserRole(c echo.Context) error
+		UnlockUser(c echo.Context) error
+		DisableUser(c echo.Context) error
+		EnableUser(c echo.Context) error
 	}
 
 	authHandler struct {
@@ -59,17 +102,19 @@ func (h *authHandler) RegisterByEmail(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, validationErrors)
 	}
 
-	fmt.Println("registerReq: ", registerReq)
-	accessToken, err := h.authUsecase.RegisterByEmail(c, h.cfg, &registerReq)
+	registerRes, err := h.authUsecase.RegisterByEmail(c, h.cfg, &registerReq)
 	if err != nil {
+		var fieldErrors utils.FieldErrors
 		switch {
+		case errors.As(err, &fieldErrors):
+			return c.JSON(http.StatusBadRequest, utils.FormatValidationError(fieldErrors))
 		case errors.Is(err, model.ErrEmailAlreadyExists):
 			return c.JSON(http.StatusConflict, map[string]string{"error": "Email is already exist"})
 		}
 
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, accessToken)
+	return c.JSON(http.StatusOK, registerRes)
 }
 
 func (h *authHandler) Login(c echo.Context) error {
@@ -86,6 +131,22 @@ func (h *authHandler) Login(c echo.Context) error {
 
 	accessToken, err := h.authUsecase.Login(c, h.cfg, &loginReq)
 	if err != nil {
+		var blocked *model.LoginBlockedError
+		if errors.As(err, &blocked) {
+			retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+			c.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		}
+
+		switch {
+		case errors.Is(err, model.ErrAccountLocked):
+			return c.JSON(http.StatusLocked, map[string]string{"error": err.Error()})
+		case errors.Is(err, model.ErrTooManyRequests):
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		case errors.Is(err, model.ErrEmailNotVerified),
+			errors.Is(err, model.ErrAccountDisabled):
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		}
+
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
 	}
 
@@ -93,64 +154,94 @@ func (h *authHandler) Login(c echo.Context) error {
 }
 
 func (h *authHandler) Logout(c echo.Context) error {
-	var logoutReq *model.LogoutReq
-	if err := c.Bind(logoutReq); err != nil {
+	var logoutReq model.LogoutReq
+	if err := c.Bind(&logoutReq); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
 
-	err := h.authUsecase.Logout(c, h.cfg, logoutReq)
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		logoutReq.AccessToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	// Browsers send the refresh token as a cookie, mobile clients in the body
+	if refreshCookie, err := c.Cookie("refresh_token"); err == nil {
+		logoutReq.RefreshToken = refreshCookie.Value
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"error": "Successfully logout"})
+	if err := h.authUsecase.Logout(c, h.cfg, &logoutReq); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidRefreshToken):
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
 
 func (h *authHandler) RefreshToken(c echo.Context) error {
-	accessToken := c.Get("accessToken").(string)
-	refreshToken := c.Get("refreshToken").(string)
-
-	// Create a Token struct to pass to ReloadToken (assuming you have such a struct)
-	reloadReq := &model.Token{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	var reloadReq model.Token
+	if err := c.Bind(&reloadReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
 	}
 
-	newTokens, err := h.authUsecase.ReloadToken(c, h.cfg, reloadReq)
+	// Access token is optional, an expired one is exactly what a client sends here
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		reloadReq.AccessToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
 
-	cookie := cookieHelper.NewCookieHelper(c, h.cfg)
+	// Browsers send the refresh token as a cookie, mobile clients in the body
+	if refreshCookie, err := c.Cookie("refresh_token"); err == nil {
+		reloadReq.RefreshToken = refreshCookie.Value
+	}
 
-	cookie.SetRefreshToken(newTokens.RefreshToken)
+	if reloadReq.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Refresh token is required"})
+	}
 
+	newTokens, err := h.authUsecase.ReloadToken(c, h.cfg, &reloadReq)
 	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidRefreshToken),
+			errors.Is(err, model.ErrExpiredRefreshToken),
+			errors.Is(err, model.ErrInvalidAccessToken),
+			errors.Is(err, model.ErrRefreshTokenReused):
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		}
+
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
-	return c.JSON(http.StatusOK, newTokens)
-
-}
-
-func (h *authHandler) FacebookLogin(c echo.Context) error {
-	fmt.Println("facebook: ", h.cfg.Facebook)
+	cookie := cookieHelper.NewCookieHelper(c, h.cfg)
 
-	redirectUrl := h.cfg.Facebook.RedirectURL
-	fmt.Println("redirect url: ", redirectUrl)
+	cookie.SetRefreshToken(newTokens.RefreshToken)
 
-	fmt.Println("echo context: ", c)
+	return c.JSON(http.StatusOK, newTokens)
 
-	return c.Redirect(http.StatusTemporaryRedirect, h.cfg.Facebook.RedirectURL)
 }
 
-func (h *authHandler) FindUserByUID(c echo.Context) error {
+// userClaims returns the claims of the access token verified by the JWT middleware
+func userClaims(c echo.Context) (*jwtAuth.AuthMapClaims, error) {
 	userJwt, ok := c.Get("user").(*jwt.Token)
 
 	if !ok {
-		return errors.New("JWT token missing or invalid")
+		return nil, errors.New("JWT token missing or invalid")
 	}
 
+	// Service tokens carry no user, so they cannot act on an account
 	claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
-	if !ok {
-		return errors.New("JWT token missing or invalid")
+	if !ok || claims.Claims == nil || claims.UserId == "" {
+		return nil, errors.New("JWT token missing or invalid")
+	}
+
+	return claims, nil
+}
+
+func (h *authHandler) FindUserByUID(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println(claims.UserId)
@@ -168,58 +259,3 @@ func (h *authHandler) FindUserByUID(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, user)
 }
-
-func (h *authHandler) GoogleLogin(c echo.Context) error {
-	redirectUrl := h.cfg.Google.AuthCodeURL("state")
-
-	return c.Redirect(http.StatusTemporaryRedirect, redirectUrl)
-}
-
-func (a *authHandler) getFacebookUserInfo(accessToken string) (*model.FacebookUser, error) {
-	client := &http.Client{}
-	req, _ := http.NewRequest("GET", "https://graph.facebook.com/me?fields=id,name,email", nil)
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var userInfo model.FacebookUser
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return nil, err
-	}
-	return &userInfo, nil
-}
-
-func (h *authHandler) FacebookCallback(c echo.Context) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	code := c.QueryParam("code")
-
-	token, err := h.cfg.Facebook.Exchange(ctx, code)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, "Failed to exchange token: "+err.Error())
-	}
-
-	userInfo, err := h.getFacebookUserInfo(token.AccessToken)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{"error": "Failed to retrieve user info"})
-	}
-
-	user, err := h.authUsecase.FindOrRegisterFacebookUser(userInfo)
-
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, "Failed to get user info: "+err.Error())
-	}
-
-	tokens := h.authUsecase.GenerateTokens(user, h.cfg)
-
-	cookie := cookieHelper.NewCookieHelper(c, h.cfg)
-
-	cookie.SetRefreshToken(tokens.RefreshToken)
-
-	return c.JSON(http.StatusOK, tokens.AccessToken)
-}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) VerifyEmail(c echo.Context) error {
+	var verifyReq model.VerifyEmailReq
+	if err := c.Bind(&verifyReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(verifyReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	if err := h.authUsecase.VerifyEmail(&verifyReq); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidVerificationToken):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Email has been verified"})
+}
+
+func (h *authHandler) ResendVerificationEmail(c echo.Context) error {
+	var resendReq model.ResendVerificationReq
+	if err := c.Bind(&resendReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(resendReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	if err := h.authUsecase.ResendVerificationEmail(h.cfg, &resendReq); err != nil {
+		switch {
+		case errors.Is(err, model.ErrTooManyRequests):
+			return c.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+		}
+
+		log.Printf("Error: Resend verification email failed: %s", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to send verification email"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "If the email is registered and unverified, a verification link has been sent"})
+}
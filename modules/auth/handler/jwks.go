@@ -0,0 +1,14 @@
+package handler
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS publishes the public keys that verify our access tokens
+func (h *authHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, jwtAuth.PublicJWKS(jwtAuth.GetKeyStore()))
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+func mfaErrorResponse(c echo.Context, err error) error {
+	var blocked *model.LoginBlockedError
+	if errors.As(err, &blocked) {
+		retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+
+	switch {
+	case errors.Is(err, model.ErrAccountLocked):
+		return c.JSON(http.StatusLocked, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrTooManyRequests):
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrInvalidMfaCode),
+		errors.Is(err, model.ErrInvalidMfaToken):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrMfaNotEnrolled),
+		errors.Is(err, model.ErrMfaAlreadyEnabled),
+		errors.Is(err, model.ErrMfaNotEnabled):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrAccountDisabled):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+func (h *authHandler) bindMfaCode(c echo.Context) (*model.MfaCodeReq, error) {
+	var codeReq model.MfaCodeReq
+	if err := c.Bind(&codeReq); err != nil {
+		return nil, c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(codeReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return nil, c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	return &codeReq, nil
+}
+
+func (h *authHandler) LoginMfa(c echo.Context) error {
+	var mfaReq model.MfaLoginReq
+	if err := c.Bind(&mfaReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(mfaReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	accessToken, err := h.authUsecase.LoginMfa(c, h.cfg, &mfaReq)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, accessToken)
+}
+
+func (h *authHandler) EnrollTotp(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	enrollRes, err := h.authUsecase.EnrollTotp(h.cfg, claims.UserId)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, enrollRes)
+}
+
+func (h *authHandler) ConfirmTotp(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	codeReq, err := h.bindMfaCode(c)
+	if codeReq == nil {
+		return err
+	}
+
+	recoveryCodes, err := h.authUsecase.ConfirmTotp(c, claims.UserId, codeReq)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodes)
+}
+
+func (h *authHandler) DisableMfa(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	codeReq, err := h.bindMfaCode(c)
+	if codeReq == nil {
+		return err
+	}
+
+	if err := h.authUsecase.DisableMfa(c, h.cfg, claims.UserId, codeReq); err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "MFA has been disabled"})
+}
+
+func (h *authHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	codeReq, err := h.bindMfaCode(c)
+	if codeReq == nil {
+		return err
+	}
+
+	recoveryCodes, err := h.authUsecase.RegenerateRecoveryCodes(c, h.cfg, claims.UserId, codeReq)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, recoveryCodes)
+}
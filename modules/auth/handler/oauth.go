@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// oauthErrorResponse answers in the error format of RFC 6749 section 5.2
+func oauthErrorResponse(c echo.Context, err error) error {
+	var oauthErr *model.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return c.JSON(http.StatusInternalServerError, model.NewOAuthError("server_error", "", http.StatusInternalServerError))
+	}
+
+	if oauthErr.Status == http.StatusUnauthorized {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	return c.JSON(oauthErr.Status, oauthErr)
+}
+
+// bindClientCredentials prefers credentials from the Authorization header over the ones
+// in the body. They are form encoded there, RFC 6749 section 2.3.1.
+func bindClientCredentials(c echo.Context, clientId *string, clientSecret *string) error {
+	id, secret, ok := c.Request().BasicAuth()
+	if !ok {
+		return nil
+	}
+
+	var err error
+	if *clientId, err = url.QueryUnescape(id); err != nil {
+		return model.ErrOAuthInvalidClient()
+	}
+	if *clientSecret, err = url.QueryUnescape(secret); err != nil {
+		return model.ErrOAuthInvalidClient()
+	}
+
+	return nil
+}
+
+func (h *authHandler) CreateOAuthClient(c echo.Context) error {
+	var createReq model.CreateOAuthClientReq
+	if err := c.Bind(&createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.FormatValidationError(err))
+	}
+
+	client, err := h.authUsecase.CreateOAuthClient(&createReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, client)
+}
+
+func (h *authHandler) ListOAuthClients(c echo.Context) error {
+	clients, err := h.authUsecase.ListOAuthClients()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, clients)
+}
+
+func (h *authHandler) DisableOAuthClient(c echo.Context) error {
+	if err := h.authUsecase.DisableOAuthClient(c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrOAuthClientNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Client disabled"})
+}
+
+// Authorize is called by the login UI with the token of the signed in user. A GET asks
+// whether the request can go through, a POST carries the consent decision of the user.
+// It is not a page a browser is sent to: clients redirect to OIDC_AUTHORIZATION_ENDPOINT,
+// where the login UI calls this with the query it got and then sends the browser on to
+// redirect_to.
+func (h *authHandler) Authorize(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	// Tokens handed out to third parties must not be able to authorize more of them
+	if claims.ClientId != "" {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Forbidden"})
+	}
+
+	var authorizeReq model.AuthorizeReq
+	if err := c.Bind(&authorizeReq); err != nil {
+		return oauthErrorResponse(c, model.ErrOAuthInvalidRequest("Invalid request"))
+	}
+
+	authorizeRes, err := h.authUsecase.Authorize(h.cfg, claims.UserId, claims.FamilyId, &authorizeReq)
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, authorizeRes)
+}
+
+func (h *authHandler) Token(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var tokenReq model.TokenReq
+	if err := c.Bind(&tokenReq); err != nil {
+		return oauthErrorResponse(c, model.ErrOAuthInvalidRequest("Invalid request"))
+	}
+
+	if err := bindClientCredentials(c, &tokenReq.ClientId, &tokenReq.ClientSecret); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	tokenRes, err := h.authUsecase.Token(c, h.cfg, &tokenReq)
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, tokenRes)
+}
+
+func (h *authHandler) Introspect(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	var introspectReq model.IntrospectReq
+	if err := c.Bind(&introspectReq); err != nil {
+		return oauthErrorResponse(c, model.ErrOAuthInvalidRequest("Invalid request"))
+	}
+
+	if err := bindClientCredentials(c, &introspectReq.ClientId, &introspectReq.ClientSecret); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	introspectRes, err := h.authUsecase.Introspect(h.cfg, &introspectReq)
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, introspectRes)
+}
+
+// RevokeToken answers 200 for any token, RFC 7009 section 2.2. Only a failed client
+// authentication or a broken request is reported.
+func (h *authHandler) RevokeToken(c echo.Context) error {
+	var revokeReq model.RevokeReq
+	if err := c.Bind(&revokeReq); err != nil {
+		return oauthErrorResponse(c, model.ErrOAuthInvalidRequest("Invalid request"))
+	}
+
+	if err := bindClientCredentials(c, &revokeReq.ClientId, &revokeReq.ClientSecret); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	if err := h.authUsecase.RevokeToken(h.cfg, &revokeReq); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) OpenIdConfiguration(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, h.authUsecase.OpenIdConfiguration(h.cfg))
+}
+
+func (h *authHandler) UserInfo(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	userInfo, err := h.authUsecase.UserInfo(claims.UserId)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+	}
+
+	return c.JSON(http.StatusOK, userInfo)
+}
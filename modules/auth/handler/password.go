@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) ForgotPassword(c echo.Context) error {
+	var forgotReq model.ForgotPasswordReq
+	if err := c.Bind(&forgotReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(forgotReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	// The answer is the same whatever happened, so it cannot tell who has an account
+	if err := h.authUsecase.ForgotPassword(h.cfg, &forgotReq); err != nil {
+		log.Printf("Error: Forgot password failed: %s", err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "If the email is registered, a reset link has been sent"})
+}
+
+func (h *authHandler) ResetPassword(c echo.Context) error {
+	var resetReq model.ResetPasswordReq
+	if err := c.Bind(&resetReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(resetReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	if err := h.authUsecase.ResetPassword(c, h.cfg, &resetReq); err != nil {
+		var fieldErrors utils.FieldErrors
+		switch {
+		case errors.As(err, &fieldErrors):
+			return c.JSON(http.StatusBadRequest, utils.FormatValidationError(fieldErrors))
+		case errors.Is(err, model.ErrInvalidResetToken):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
+
+func (h *authHandler) ChangePassword(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	var changeReq model.ChangePasswordReq
+	if err := c.Bind(&changeReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(changeReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	if err := h.authUsecase.ChangePassword(c, claims.UserId, claims.FamilyId, &changeReq); err != nil {
+		var fieldErrors utils.FieldErrors
+		switch {
+		case errors.As(err, &fieldErrors):
+			return c.JSON(http.StatusBadRequest, utils.FormatValidationError(fieldErrors))
+		case errors.Is(err, model.ErrInvalidPassword):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password has been changed"})
+}
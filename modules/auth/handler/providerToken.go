@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProviderToken hands a trusted service a token to call the API of a social login
+// provider as the user
+func (h *authHandler) ProviderToken(c echo.Context) error {
+	tokenRes, err := h.authUsecase.ProviderToken(c.Param("id"), c.Param("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrUnknownLoginProvider),
+			errors.Is(err, model.ErrProviderTokenNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		case errors.Is(err, model.ErrProviderTokenExpired):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	return c.JSON(http.StatusOK, tokenRes)
+}
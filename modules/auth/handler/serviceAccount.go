@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) CreateServiceAccount(c echo.Context) error {
+	var createReq model.CreateServiceAccountReq
+	if err := c.Bind(&createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(createReq); err != nil {
+		return c.JSON(http.StatusBadRequest, utils.FormatValidationError(err))
+	}
+
+	account, err := h.authUsecase.CreateServiceAccount(&createReq)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusCreated, account)
+}
+
+func (h *authHandler) ListServiceAccounts(c echo.Context) error {
+	accounts, err := h.authUsecase.ListServiceAccounts()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, accounts)
+}
+
+func (h *authHandler) RotateServiceAccountSecret(c echo.Context) error {
+	account, err := h.authUsecase.RotateServiceAccountSecret(h.cfg, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrServiceAccountNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
+func (h *authHandler) DisableServiceAccount(c echo.Context) error {
+	if err := h.authUsecase.DisableServiceAccount(c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrServiceAccountNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Service account disabled"})
+}
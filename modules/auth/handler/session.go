@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func (h *authHandler) ListSessions(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	sessions, err := h.authUsecase.ListSessions(claims.UserId, claims.FamilyId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
+func (h *authHandler) RevokeSession(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.authUsecase.RevokeSession(claims.UserId, c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, model.ErrSessionNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Session revoked"})
+}
+
+func (h *authHandler) RevokeOtherSessions(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.authUsecase.RevokeOtherSessions(claims.UserId, claims.FamilyId); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Other sessions revoked"})
+}
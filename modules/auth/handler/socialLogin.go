@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func socialLoginErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrUnknownLoginProvider),
+		errors.Is(err, model.ErrIdentityNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrInvalidSocialLoginState),
+		errors.Is(err, model.ErrSocialEmailRequired),
+		errors.Is(err, model.ErrIdTokenLoginNotSupported):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrSocialLoginFailed):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrEmailAlreadyExists):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Email is already used by another account, log in to it and link the provider instead"})
+	case errors.Is(err, model.ErrIdentityAlreadyLinked),
+		errors.Is(err, model.ErrLastLoginMethod):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrEmailNotVerified),
+		errors.Is(err, model.ErrAccountDisabled):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+// SocialLogin sends the browser to the login page of the provider in the path
+func (h *authHandler) SocialLogin(c echo.Context) error {
+	redirectUrl, err := h.authUsecase.BeginSocialLogin(c, h.cfg, c.Param("provider"))
+	if err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.Redirect(http.StatusTemporaryRedirect, redirectUrl)
+}
+
+// SocialCallback is where the provider sends the browser back to with a code. Providers
+// with form_post post it instead.
+func (h *authHandler) SocialCallback(c echo.Context) error {
+	var callbackReq model.SocialCallbackReq
+	if err := c.Bind(&callbackReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	if err := h.validator.Struct(callbackReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	loginRes, err := h.authUsecase.FinishSocialLogin(c, h.cfg, c.Param("provider"), &callbackReq)
+	if err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, loginRes)
+}
+
+// SocialIdTokenLogin takes an ID token the client got from the provider in the path
+func (h *authHandler) SocialIdTokenLogin(c echo.Context) error {
+	var idTokenReq model.SocialIdTokenReq
+	if err := c.Bind(&idTokenReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(idTokenReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	accessToken, err := h.authUsecase.SocialIdTokenLogin(c, h.cfg, c.Param("provider"), &idTokenReq)
+	if err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, accessToken)
+}
+
+// LinkIdentity returns the url of the provider to link to the signed in user. The
+// client sends the browser there, and the callback links the identity.
+func (h *authHandler) LinkIdentity(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	linkRes, err := h.authUsecase.BeginIdentityLink(c, h.cfg, claims.UserId, c.Param("provider"))
+	if err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, linkRes)
+}
+
+func (h *authHandler) ListIdentities(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	identities, err := h.authUsecase.ListIdentities(claims.UserId)
+	if err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, identities)
+}
+
+func (h *authHandler) UnlinkIdentity(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	if err := h.authUsecase.UnlinkIdentity(c, claims.UserId, c.Param("provider")); err != nil {
+		return socialLoginErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Identity unlinked"})
+}
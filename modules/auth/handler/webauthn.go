@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"go-auth/modules/auth/model"
+	"go-auth/utils"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func passkeyErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrInvalidPasskey),
+		errors.Is(err, model.ErrInvalidWebauthnSession):
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	case errors.Is(err, model.ErrEmailNotVerified),
+		errors.Is(err, model.ErrAccountDisabled):
+		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
+
+func (h *authHandler) BeginPasskeyRegistration(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	creation, err := h.authUsecase.BeginPasskeyRegistration(claims.UserId)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, creation)
+}
+
+// FinishPasskeyRegistration takes the PublicKeyCredential of navigator.credentials.create
+// as the body and an optional ?name= to tell the passkeys of the user apart.
+func (h *authHandler) FinishPasskeyRegistration(c echo.Context) error {
+	claims, err := userClaims(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+	}
+
+	passkey, err := h.authUsecase.FinishPasskeyRegistration(claims.UserId, c.QueryParam("name"), c.Request().Body)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, passkey)
+}
+
+func (h *authHandler) BeginPasskeyLogin(c echo.Context) error {
+	var loginReq model.WebauthnLoginReq
+	if err := c.Bind(&loginReq); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	}
+
+	if err := h.validator.Struct(loginReq); err != nil {
+		validationErrors := utils.FormatValidationError(err)
+		log.Printf("Error: Validate data failed: %s", err.Error())
+		return c.JSON(http.StatusBadRequest, validationErrors)
+	}
+
+	assertion, err := h.authUsecase.BeginPasskeyLogin(&loginReq)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, assertion)
+}
+
+// FinishPasskeyLogin takes the PublicKeyCredential of navigator.credentials.get as the body.
+func (h *authHandler) FinishPasskeyLogin(c echo.Context) error {
+	accessToken, err := h.authUsecase.FinishPasskeyLogin(c, h.cfg, c.Request().Body)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, accessToken)
+}
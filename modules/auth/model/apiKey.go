@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ApiKeyScopeOpenId lets a key read the profile from userinfo
+	ApiKeyScopeOpenId = "openid"
+	// ApiKeyScopeUsersRead lets a key read the user from /auth/users
+	ApiKeyScopeUsersRead = "users:read"
+)
+
+type (
+	// ApiKey is a long lived personal key. Only a hash of the key is stored, Prefix keeps
+	// enough of it for the user to recognise the key in a list.
+	ApiKey struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+		UserId     string             `bson:"user_id" json:"-"`
+		Name       string             `bson:"name" json:"name"`
+		KeyHash    string             `bson:"key_hash" json:"-"`
+		Prefix     string             `bson:"prefix" json:"prefix"`
+		Scopes     []string           `bson:"scopes" json:"scopes"`
+		ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+		LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+		LastUsedIp string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+		Revoked    bool               `bson:"revoked" json:"-"`
+		CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	}
+
+	CreateApiKeyReq struct {
+		Name   string   `json:"name" validate:"required,max=255"`
+		Scopes []string `json:"scopes" validate:"required,min=1,dive,required,oneof=openid users:read"`
+		// ExpiresInDays is optional, a key without it never expires
+		ExpiresInDays int `json:"expires_in_days" validate:"omitempty,min=1,max=3650"`
+	}
+
+	CreateApiKeyRes struct {
+		*ApiKey
+		Key string `json:"key"`
+	}
+)
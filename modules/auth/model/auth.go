@@ -6,10 +6,16 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	SecurityEventAccountDisabled   = "account_disabled"
+	SecurityEventAccountEnabled    = "account_enabled"
+)
+
 type (
 	LoginReq struct {
 		Email    string `json:"email" validate:"required,email,max=255"`
-		Password string `json:"password" validate:"required,max=32"`
+		Password string `json:"password" validate:"required,max=128"`
 	}
 
 	RefreshTokenReq struct {
@@ -19,11 +25,12 @@ type (
 
 	RegisterReq struct {
 		Email    string `json:"email" validate:"required,email,max=255"`
-		Password string `json:"password" validate:"required,max=32"`
+		Password string `json:"password" validate:"required,max=128"`
 	}
 
 	RegisterRes struct {
-		AccessToken string `json:"access_token"`
+		AccessToken string `json:"access_token,omitempty"`
+		Message     string `json:"message,omitempty"`
 	}
 
 	LoginRes struct {
@@ -37,6 +44,7 @@ type (
 
 	LogoutReq struct {
 		RefreshToken string `json:"refresh_token"`
+		AccessToken  string `json:"-"`
 	}
 
 	UserPassport struct {
@@ -44,46 +52,74 @@ type (
 		Password      string `json:"password"`
 		OauthProvider string `json:"oauth_provider"`
 		OauthId       string `json:"oauth_id"`
+		EmailVerified bool   `json:"email_verified"`
 
 		Role string `json:"role"`
 	}
 
 	User struct {
-		ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-		Email         string             `bson:"email" json:"email"`
-		Password      string             `bson:"password" json:"password,omitempty"`
-		OauthProvider string             `bson:"oauth_provider" json:"oauth_provider"`
-		OauthId       string             `bson:"oauth_id" json:"oauth_id,omitempty"`
-		Role          string             `bson:"role" json:"role"`
-		CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
-		UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+		ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+		Email           string             `bson:"email" json:"email"`
+		Password        string             `bson:"password" json:"password,omitempty"`
+		OauthProvider   string             `bson:"oauth_provider" json:"oauth_provider"`
+		OauthId         string             `bson:"oauth_id" json:"oauth_id,omitempty"`
+		Role            string             `bson:"role" json:"role"`
+		EmailVerified   bool               `bson:"email_verified" json:"email_verified"`
+		MfaEnabled      bool               `bson:"mfa_enabled" json:"mfa_enabled"`
+		TotpSecret      string             `bson:"totp_secret,omitempty" json:"-"`
+		PendingTotp     string             `bson:"pending_totp_secret,omitempty" json:"-"`
+		RecoveryCodes   []string           `bson:"recovery_codes,omitempty" json:"-"`
+		Passkeys        []Passkey          `bson:"passkeys,omitempty" json:"-"`
+		TokenVersion    int64              `bson:"token_version" json:"-"`
+		PasswordHistory []string           `bson:"password_history,omitempty" json:"-"`
+		Identities      []Identity         `bson:"identities,omitempty" json:"-"`
+		// Disabled users cannot sign in or refresh until an admin enables them again
+		Disabled  bool      `bson:"disabled,omitempty" json:"disabled,omitempty"`
+		CreatedAt time.Time `bson:"created_at" json:"created_at"`
+		UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 	}
 
 	Blacklist struct {
-		RefreshToken string    `bson:"refresh_token"`
-		ExpiresAt    time.Time `bson:"expires_at"`
+		Jti       string    `bson:"jti"`
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+
+	RefreshTokenRecord struct {
+		Jti       string     `bson:"jti"`
+		FamilyId  string     `bson:"family_id"`
+		UserId    string     `bson:"user_id"`
+		Revoked   bool       `bson:"revoked"`
+		RotatedAt *time.Time `bson:"rotated_at"`
+		ExpiresAt time.Time  `bson:"expires_at"`
+		CreatedAt time.Time  `bson:"created_at"`
+	}
+
+	SecurityEvent struct {
+		UserId    string    `bson:"user_id" json:"user_id"`
+		Type      string    `bson:"type" json:"type"`
+		FamilyId  string    `bson:"family_id,omitempty" json:"family_id,omitempty"`
+		Jti       string    `bson:"jti,omitempty" json:"jti,omitempty"`
+		IpAddress string    `bson:"ip_address" json:"ip_address"`
+		UserAgent string    `bson:"user_agent" json:"user_agent"`
+		CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	}
 
+	// AccessToken is the login response. When the account has MFA enabled it carries an
+	// MFA challenge token to exchange at /auth/login/mfa instead of the access token.
 	AccessToken struct {
-		AccessToken string `json:"access_token"`
+		AccessToken string `json:"access_token,omitempty"`
+		IdToken     string `json:"id_token,omitempty"`
+		MfaRequired bool   `json:"mfa_required,omitempty"`
+		MfaToken    string `json:"mfa_token,omitempty"`
 	}
 
 	Token struct {
 		AccessToken  string `json:"access_token"`
 		RefreshToken string `json:"refresh_token"`
+		IdToken      string `json:"id_token,omitempty"`
 	}
 
-	FacebookUser struct {
-		OauthId       string `json:"id"`
-		Name          string `json:"name"`
-		Email         string `json:"email"`
-		OauthProvider string `json:"oauth_provider"`
-	}
-
-	GoogleUser struct {
-		OauthId       string `json:"sub"`
-		Name          string `json:"name"`
-		Email         string `json:"email"`
-		OauthProvider string `json:"oauth_provider"`
+	UpdateRoleReq struct {
+		Role string `json:"role" validate:"required,oneof=user admin"`
 	}
 )
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type (
+	VerifyEmailReq struct {
+		Token string `json:"token" validate:"required,max=128"`
+	}
+
+	ResendVerificationReq struct {
+		Email string `json:"email" validate:"required,email,max=255"`
+	}
+
+	EmailVerificationToken struct {
+		TokenHash string     `bson:"token_hash"`
+		UserId    string     `bson:"user_id"`
+		Email     string     `bson:"email"`
+		ExpiresAt time.Time  `bson:"expires_at"`
+		UsedAt    *time.Time `bson:"used_at"`
+		CreatedAt time.Time  `bson:"created_at"`
+	}
+)
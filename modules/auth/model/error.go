@@ -13,3 +13,67 @@ var ErrAddBlacklistTokenFailed = errors.New("Add blacklist token failed")
 var ErrFailedToHashPassword = errors.New("Failed to hash password")
 
 var ErrInvalidAccessToken = errors.New("Invalid access token")
+
+var ErrRefreshTokenReused = errors.New("Refresh token reuse detected")
+
+var ErrSessionNotFound = errors.New("Session not found")
+
+var ErrInvalidResetToken = errors.New("Invalid or expired reset token")
+
+var ErrInvalidVerificationToken = errors.New("Invalid or expired verification token")
+
+var ErrEmailNotVerified = errors.New("Email is not verified")
+
+var ErrTooManyRequests = errors.New("Too many requests, try again later")
+
+var ErrInvalidMfaCode = errors.New("Invalid MFA code")
+
+var ErrInvalidMfaToken = errors.New("Invalid or expired MFA token")
+
+var ErrMfaNotEnrolled = errors.New("MFA enrollment has not been started")
+
+var ErrMfaAlreadyEnabled = errors.New("MFA is already enabled")
+
+var ErrMfaNotEnabled = errors.New("MFA is not enabled")
+
+var ErrInvalidWebauthnSession = errors.New("Invalid or expired WebAuthn ceremony")
+
+var ErrInvalidPasskey = errors.New("Invalid passkey")
+
+var ErrOAuthClientNotFound = errors.New("OAuth client not found")
+
+var ErrServiceAccountNotFound = errors.New("Service account not found")
+
+var ErrInvalidApiKey = errors.New("Invalid API key")
+
+var ErrApiKeyNotFound = errors.New("API key not found")
+
+var ErrUserNotFound = errors.New("User not found")
+
+var ErrAccountLocked = errors.New("Account is temporarily locked, try again later")
+
+var ErrAccountDisabled = errors.New("Account is disabled")
+
+var ErrInvalidPassword = errors.New("Current password is invalid")
+
+var ErrInvalidCredentials = errors.New("Invalid email or password")
+
+var ErrUnknownLoginProvider = errors.New("Unknown login provider")
+
+var ErrInvalidSocialLoginState = errors.New("Invalid or expired login state")
+
+var ErrSocialLoginFailed = errors.New("Login with the provider failed")
+
+var ErrSocialEmailRequired = errors.New("The provider did not share an email address")
+
+var ErrIdTokenLoginNotSupported = errors.New("The provider does not support login with an ID token")
+
+var ErrIdentityAlreadyLinked = errors.New("The identity is already linked to an account")
+
+var ErrIdentityNotFound = errors.New("Identity not found")
+
+var ErrLastLoginMethod = errors.New("Cannot unlink the last way to log in to the account")
+
+var ErrProviderTokenNotFound = errors.New("No token of the provider is stored for the user")
+
+var ErrProviderTokenExpired = errors.New("The token of the provider expired, the user has to log in with it again")
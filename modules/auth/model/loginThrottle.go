@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+)
+
+type (
+	// LoginBlock keeps an account or an IP address from trying to log in for a while. A
+	// lock follows too many failures, anything short of that only earns a delay.
+	LoginBlock struct {
+		Locked     bool
+		RetryAfter time.Duration
+	}
+
+	// LoginBlockedError is ErrAccountLocked or ErrTooManyRequests, along with when the
+	// next attempt is allowed.
+	LoginBlockedError struct {
+		Err        error
+		RetryAfter time.Duration
+	}
+)
+
+func (e *LoginBlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,28 @@
+package model
+
+const (
+	SecurityEventMfaEnabled               = "mfa_enabled"
+	SecurityEventMfaDisabled              = "mfa_disabled"
+	SecurityEventRecoveryCodesRegenerated = "mfa_recovery_codes_regenerated"
+	SecurityEventRecoveryCodeUsed         = "mfa_recovery_code_used"
+)
+
+type (
+	TotpEnrollRes struct {
+		Secret     string `json:"secret"`
+		OtpauthUri string `json:"otpauth_uri"`
+	}
+
+	MfaCodeReq struct {
+		Code string `json:"code" validate:"required,max=32"`
+	}
+
+	MfaLoginReq struct {
+		MfaToken string `json:"mfa_token" validate:"required,max=128"`
+		Code     string `json:"code" validate:"required,max=32"`
+	}
+
+	RecoveryCodesRes struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+)
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	CodeChallengeMethodS256    = "S256"
+	ScopeOpenId                = "openid"
+)
+
+type (
+	// OAuthError is an error response of RFC 6749 section 5.2
+	OAuthError struct {
+		Code        string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+		Status      int    `json:"-"`
+	}
+
+	OAuthClient struct {
+		ClientId      string    `bson:"client_id" json:"client_id"`
+		SecretHash    string    `bson:"secret_hash,omitempty" json:"-"`
+		Name          string    `bson:"name" json:"name"`
+		RedirectUris  []string  `bson:"redirect_uris" json:"redirect_uris"`
+		AllowedScopes []string  `bson:"allowed_scopes" json:"allowed_scopes"`
+		Public        bool      `bson:"public" json:"public"`
+		Disabled      bool      `bson:"disabled" json:"disabled"`
+		CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+		UpdatedAt     time.Time `bson:"updated_at" json:"updated_at"`
+	}
+
+	CreateOAuthClientReq struct {
+		Name          string   `json:"name" validate:"required,max=255"`
+		RedirectUris  []string `json:"redirect_uris" validate:"required,min=1,dive,url,max=2048"`
+		AllowedScopes []string `json:"allowed_scopes" validate:"required,min=1,dive,required,max=64"`
+		Public        bool     `json:"public"`
+	}
+
+	CreateOAuthClientRes struct {
+		*OAuthClient
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	OAuthConsent struct {
+		UserId    string    `bson:"user_id" json:"user_id"`
+		ClientId  string    `bson:"client_id" json:"client_id"`
+		Scopes    []string  `bson:"scopes" json:"scopes"`
+		CreatedAt time.Time `bson:"created_at" json:"created_at"`
+		UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	}
+
+	AuthorizeReq struct {
+		ResponseType        string `json:"response_type" query:"response_type" form:"response_type"`
+		ClientId            string `json:"client_id" query:"client_id" form:"client_id"`
+		RedirectUri         string `json:"redirect_uri" query:"redirect_uri" form:"redirect_uri"`
+		Scope               string `json:"scope" query:"scope" form:"scope"`
+		State               string `json:"state" query:"state" form:"state"`
+		Nonce               string `json:"nonce" query:"nonce" form:"nonce"`
+		CodeChallenge       string `json:"code_challenge" query:"code_challenge" form:"code_challenge"`
+		CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" form:"code_challenge_method"`
+		Approve             *bool  `json:"approve" form:"approve"`
+	}
+
+	// AuthorizeRes tells the login UI where to send the browser, or which consent to ask for
+	AuthorizeRes struct {
+		RedirectTo      string   `json:"redirect_to,omitempty"`
+		ConsentRequired bool     `json:"consent_required,omitempty"`
+		ClientName      string   `json:"client_name,omitempty"`
+		Scopes          []string `json:"scopes,omitempty"`
+	}
+
+	AuthorizationCode struct {
+		ClientId    string `json:"client_id"`
+		UserId      string `json:"user_id"`
+		RedirectUri string `json:"redirect_uri"`
+		// RedirectUriSent is set when the authorize request named the redirect_uri, which
+		// the token request then has to repeat
+		RedirectUriSent bool      `json:"redirect_uri_sent"`
+		Scope           string    `json:"scope"`
+		Nonce           string    `json:"nonce"`
+		CodeChallenge   string    `json:"code_challenge"`
+		AuthTime        time.Time `json:"auth_time"`
+	}
+
+	TokenReq struct {
+		GrantType    string `form:"grant_type"`
+		Code         string `form:"code"`
+		RedirectUri  string `form:"redirect_uri"`
+		CodeVerifier string `form:"code_verifier"`
+		RefreshToken string `form:"refresh_token"`
+		Scope        string `form:"scope"`
+		ClientId     string `form:"client_id"`
+		ClientSecret string `form:"client_secret"`
+	}
+
+	// TokenRes is a successful response of RFC 6749 section 5.1
+	TokenRes struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		IdToken      string `json:"id_token,omitempty"`
+		Scope        string `json:"scope,omitempty"`
+	}
+)
+
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+func NewOAuthError(code string, description string, status int) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+		Status:      status,
+	}
+}
+
+func ErrOAuthInvalidRequest(description string) *OAuthError {
+	return NewOAuthError("invalid_request", description, http.StatusBadRequest)
+}
+
+func ErrOAuthInvalidClient() *OAuthError {
+	return NewOAuthError("invalid_client", "Client authentication failed", http.StatusUnauthorized)
+}
+
+func ErrOAuthInvalidGrant(description string) *OAuthError {
+	return NewOAuthError("invalid_grant", description, http.StatusBadRequest)
+}
+
+func ErrOAuthInvalidScope(description string) *OAuthError {
+	return NewOAuthError("invalid_scope", description, http.StatusBadRequest)
+}
+
+func ErrOAuthUnsupportedGrantType() *OAuthError {
+	return NewOAuthError("unsupported_grant_type", "", http.StatusBadRequest)
+}
+
+func ErrOAuthUnauthorizedClient(description string) *OAuthError {
+	return NewOAuthError("unauthorized_client", description, http.StatusBadRequest)
+}
+
+func ErrOAuthAccessDenied() *OAuthError {
+	return NewOAuthError("access_denied", "The user denied the request", http.StatusForbidden)
+}
+
+func ErrOAuthUnsupportedResponseType() *OAuthError {
+	return NewOAuthError("unsupported_response_type", "", http.StatusBadRequest)
+}
+
+type (
+	IntrospectReq struct {
+		Token         string `form:"token"`
+		TokenTypeHint string `form:"token_type_hint"`
+		ClientId      string `form:"client_id"`
+		ClientSecret  string `form:"client_secret"`
+	}
+
+	// IntrospectRes is the response of RFC 7662 section 2.2. Inactive tokens only ever
+	// report active false.
+	IntrospectRes struct {
+		Active    bool   `json:"active"`
+		Sub       string `json:"sub,omitempty"`
+		Scope     string `json:"scope,omitempty"`
+		ClientId  string `json:"client_id,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		Iat       int64  `json:"iat,omitempty"`
+		Iss       string `json:"iss,omitempty"`
+		Jti       string `json:"jti,omitempty"`
+	}
+)
+
+type RevokeReq struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
@@ -0,0 +1,27 @@
+package model
+
+type (
+	OpenIdConfiguration struct {
+		Issuer                            string   `json:"issuer"`
+		AuthorizationEndpoint             string   `json:"authorization_endpoint,omitempty"`
+		TokenEndpoint                     string   `json:"token_endpoint,omitempty"`
+		IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+		RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+		UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+		JwksUri                           string   `json:"jwks_uri"`
+		ScopesSupported                   []string `json:"scopes_supported"`
+		ResponseTypesSupported            []string `json:"response_types_supported"`
+		GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+		CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+		TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+		SubjectTypesSupported             []string `json:"subject_types_supported"`
+		IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+		ClaimsSupported                   []string `json:"claims_supported"`
+	}
+
+	UserInfo struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email,omitempty"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+)
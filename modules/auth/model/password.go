@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const (
+	SecurityEventPasswordReset   = "password_reset"
+	SecurityEventPasswordChanged = "password_changed"
+)
+
+type (
+	ForgotPasswordReq struct {
+		Email string `json:"email" validate:"required,email,max=255"`
+	}
+
+	ResetPasswordReq struct {
+		Token    string `json:"token" validate:"required,max=128"`
+		Password string `json:"password" validate:"required,max=128"`
+	}
+
+	ChangePasswordReq struct {
+		CurrentPassword string `json:"current_password" validate:"required,max=128"`
+		Password        string `json:"password" validate:"required,max=128"`
+	}
+
+	PasswordResetToken struct {
+		TokenHash string     `bson:"token_hash"`
+		UserId    string     `bson:"user_id"`
+		ExpiresAt time.Time  `bson:"expires_at"`
+		UsedAt    *time.Time `bson:"used_at"`
+		CreatedAt time.Time  `bson:"created_at"`
+	}
+)
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+type (
+	// ProviderToken is the token a social login provider issued for the user, kept to call
+	// the API of the provider on their behalf. Both tokens are sealed by the token vault.
+	ProviderToken struct {
+		UserId       string    `bson:"user_id"`
+		Provider     string    `bson:"provider"`
+		AccessToken  string    `bson:"access_token"`
+		RefreshToken string    `bson:"refresh_token,omitempty"`
+		TokenType    string    `bson:"token_type"`
+		Expiry       time.Time `bson:"expiry"`
+		UpdatedAt    time.Time `bson:"updated_at"`
+	}
+
+	ProviderTokenRes struct {
+		AccessToken string     `json:"access_token"`
+		TokenType   string     `json:"token_type"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	}
+)
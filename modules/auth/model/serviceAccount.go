@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+const GrantTypeClientCredentials = "client_credentials"
+
+type (
+	// ServiceAccount is a machine principal. It signs in with the client credentials grant
+	// and only ever holds the scopes it was given.
+	ServiceAccount struct {
+		ClientId                string     `bson:"client_id" json:"client_id"`
+		SecretHash              string     `bson:"secret_hash" json:"-"`
+		PreviousSecretHash      string     `bson:"previous_secret_hash,omitempty" json:"-"`
+		PreviousSecretExpiresAt *time.Time `bson:"previous_secret_expires_at,omitempty" json:"-"`
+		Name                    string     `bson:"name" json:"name"`
+		Scopes                  []string   `bson:"scopes" json:"scopes"`
+		Disabled                bool       `bson:"disabled" json:"disabled"`
+		SecretRotatedAt         time.Time  `bson:"secret_rotated_at" json:"secret_rotated_at"`
+		CreatedAt               time.Time  `bson:"created_at" json:"created_at"`
+		UpdatedAt               time.Time  `bson:"updated_at" json:"updated_at"`
+	}
+
+	CreateServiceAccountReq struct {
+		Name   string   `json:"name" validate:"required,max=255"`
+		Scopes []string `json:"scopes" validate:"required,min=1,dive,required,max=64"`
+	}
+
+	ServiceAccountSecretRes struct {
+		*ServiceAccount
+		ClientSecret string `json:"client_secret"`
+	}
+)
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type (
+	// Session is a signed-in device. Its id is the refresh token family id, so every
+	// refresh token rotated from one login belongs to the same session.
+	Session struct {
+		ID         string    `bson:"_id" json:"id"`
+		UserId     string    `bson:"user_id" json:"-"`
+		UserAgent  string    `bson:"user_agent" json:"user_agent"`
+		IpAddress  string    `bson:"ip_address" json:"ip_address"`
+		Revoked    bool      `bson:"revoked" json:"-"`
+		CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+		LastUsedAt time.Time `bson:"last_used_at" json:"last_used_at"`
+		ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+		Current    bool      `bson:"-" json:"current"`
+	}
+)
@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+const (
+	SecurityEventIdentityLinked   = "identity_linked"
+	SecurityEventIdentityUnlinked = "identity_unlinked"
+)
+
+type (
+	// Identity is an account of the user at a social login provider, which the user can
+	// log in with
+	Identity struct {
+		Provider string    `bson:"provider" json:"provider"`
+		Subject  string    `bson:"subject" json:"subject"`
+		Email    string    `bson:"email,omitempty" json:"email,omitempty"`
+		LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
+	}
+
+	// SocialLoginState is what the callback of a social login needs from its start. It is
+	// stored under the hash of the state, which the browser keeps in a cookie. LinkUserId
+	// is set when a signed in user links the provider instead of logging in with it.
+	SocialLoginState struct {
+		Provider     string `json:"provider"`
+		Nonce        string `json:"nonce"`
+		CodeVerifier string `json:"code_verifier"`
+		LinkUserId   string `json:"link_user_id,omitempty"`
+	}
+
+	// SocialLoginRes is the access token of a login, or the identity that was linked
+	SocialLoginRes struct {
+		*AccessToken
+		Identity *Identity `json:"identity,omitempty"`
+	}
+
+	SocialLinkRes struct {
+		Url string `json:"url"`
+	}
+
+	SocialCallbackReq struct {
+		Code             string `query:"code" form:"code" validate:"max=2048"`
+		State            string `query:"state" form:"state" validate:"required,max=128"`
+		Error            string `query:"error" form:"error" validate:"max=255"`
+		ErrorDescription string `query:"error_description" form:"error_description" validate:"max=1024"`
+	}
+
+	// SocialIdTokenReq signs in with an ID token the client got from the provider itself,
+	// such as Google One Tap or a mobile SDK does. The nonce is checked when given.
+	SocialIdTokenReq struct {
+		IdToken string `json:"id_token" validate:"required,max=8192"`
+		Nonce   string `json:"nonce" validate:"max=255"`
+	}
+)
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+const (
+	SecurityEventPasskeyCloneWarning = "passkey_clone_warning"
+)
+
+type (
+	// Passkey is a WebAuthn credential registered by a user
+	Passkey struct {
+		CredentialId    []byte    `bson:"credential_id" json:"credential_id"`
+		PublicKey       []byte    `bson:"public_key" json:"-"`
+		AttestationType string    `bson:"attestation_type" json:"-"`
+		Transports      []string  `bson:"transports" json:"transports"`
+		AAGUID          []byte    `bson:"aaguid" json:"-"`
+		SignCount       uint32    `bson:"sign_count" json:"-"`
+		BackupEligible  bool      `bson:"backup_eligible" json:"backup_eligible"`
+		BackupState     bool      `bson:"backup_state" json:"backup_state"`
+		Name            string    `bson:"name" json:"name"`
+		CreatedAt       time.Time `bson:"created_at" json:"created_at"`
+		LastUsedAt      time.Time `bson:"last_used_at" json:"last_used_at"`
+	}
+
+	WebauthnLoginReq struct {
+		Email string `json:"email" validate:"omitempty,email,max=255"`
+	}
+)
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *authRepository) apiKeyCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("ApiKeys")
+}
+
+func (r *authRepository) AddApiKey(apiKey *model.ApiKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := r.apiKeyCollection().InsertOne(ctx, apiKey)
+	if err != nil {
+		return err
+	}
+
+	apiKey.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *authRepository) FindApiKeyByHash(keyHash string) (*model.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var apiKey model.ApiKey
+	err := r.apiKeyCollection().FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&apiKey)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrApiKeyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (r *authRepository) FindApiKeysByUserId(userId string) ([]*model.ApiKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "revoked": false}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.apiKeyCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	apiKeys := make([]*model.ApiKey, 0)
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// TouchApiKey records the use of the key. Writes are skipped while the last one is
+// younger than interval, so a busy key does not cost a write per request.
+func (r *authRepository) TouchApiKey(objectID primitive.ObjectID, ipAddress string, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := bson.M{
+		"_id": objectID,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-interval)}},
+			bson.M{"last_used_ip": bson.M{"$ne": ipAddress}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"last_used_at": now,
+		"last_used_ip": ipAddress,
+	}}
+
+	_, err := r.apiKeyCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *authRepository) RevokeApiKey(userId string, objectID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID, "user_id": userId, "revoked": false}
+	update := bson.M{"$set": bson.M{"revoked": true}}
+
+	result, err := r.apiKeyCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrApiKeyNotFound
+	}
+
+	return nil
+}
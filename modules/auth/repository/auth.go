@@ -16,14 +16,61 @@ import (
 type (
 	AuthRepository interface {
 		userCollection() *mongo.Collection
-		blacklistCollection() *mongo.Collection
+		refreshTokenCollection() *mongo.Collection
+		securityEventCollection() *mongo.Collection
+		passwordResetCollection() *mongo.Collection
+		emailVerificationCollection() *mongo.Collection
+		apiKeyCollection() *mongo.Collection
 		FindOneUserByEmail(email string) (*model.User, error)
-		AccessToken(cfg *config.Config, claims *jwtAuth.Claims) string
-		RefreshToken(cfg *config.Config, claims *jwtAuth.Claims) string
+		AccessToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error)
+		RefreshToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error)
+		FindRefreshToken(jti string) (*model.RefreshTokenRecord, error)
+		RotateRefreshToken(jti string) (bool, error)
+		RevokeRefreshTokenFamily(familyId string) error
+		AddSecurityEvent(event *model.SecurityEvent) error
+		AddPasswordResetToken(token *model.PasswordResetToken) error
+		FindPasswordResetToken(tokenHash string) (*model.PasswordResetToken, error)
+		ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error)
+		UpdateUserPassword(objectID primitive.ObjectID, hashedPassword string, historySize int) error
+		RehashUserPassword(objectID primitive.ObjectID, oldHash string, newHash string) error
+		UpdateUserRole(objectID primitive.ObjectID, role string) error
+		SetUserDisabled(objectID primitive.ObjectID, disabled bool) error
+		RevokeRefreshTokensByUserId(userId string) error
+		AddEmailVerificationToken(token *model.EmailVerificationToken) error
+		ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error)
+		MarkEmailVerified(objectID primitive.ObjectID, email string) error
+		AllowEmailVerificationResend(email string, interval time.Duration) (bool, error)
+		SetPendingTotpSecret(objectID primitive.ObjectID, secret string) error
+		EnableMfa(objectID primitive.ObjectID, secret string, recoveryCodeHashes []string) error
+		DisableMfa(objectID primitive.ObjectID) error
+		SetRecoveryCodes(objectID primitive.ObjectID, recoveryCodeHashes []string) error
+		ConsumeRecoveryCode(objectID primitive.ObjectID, recoveryCodeHash string) (bool, error)
+		MarkTotpStepUsed(userId string, step int64, ttl time.Duration) (bool, error)
+		AddMfaChallenge(tokenHash string, userId string, ttl time.Duration) error
+		FindMfaChallenge(tokenHash string, maxAttempts int64) (string, error)
+		DeleteMfaChallenge(tokenHash string) error
+		AddPasskey(objectID primitive.ObjectID, passkey *model.Passkey) error
+		UpdatePasskeySignCount(objectID primitive.ObjectID, credentialId []byte, signCount uint32) error
+		SaveWebauthnSession(key string, data []byte, ttl time.Duration) error
+		TakeWebauthnSession(key string) ([]byte, error)
+		AddLoginFailure(subject string, window time.Duration) (int64, error)
+		BlockLogin(subject string, block *model.LoginBlock) error
+		FindLoginBlock(subject string) (*model.LoginBlock, error)
+		ResetLoginFailures(subject string) error
+		AddApiKey(apiKey *model.ApiKey) error
+		FindApiKeyByHash(keyHash string) (*model.ApiKey, error)
+		FindApiKeysByUserId(userId string) ([]*model.ApiKey, error)
+		TouchApiKey(objectID primitive.ObjectID, ipAddress string, interval time.Duration) error
+		RevokeApiKey(userId string, objectID primitive.ObjectID) error
 		AddUser(userPassport *model.UserPassport) (*model.User, error)
-		AddBlacklistToken(refreshToken string, expiration time.Time) error
-		IsBlacklistExist(refreshToken string) (bool, error)
-		FindByProviderId(id string) (*model.User, error)
+		FindByProviderId(provider string, id string) (*model.User, error)
+		AddUserIdentity(objectID primitive.ObjectID, identity *model.Identity) error
+		RemoveUserIdentity(objectID primitive.ObjectID, provider string) (bool, error)
+		SaveProviderToken(token *model.ProviderToken) error
+		FindProviderToken(userId string, provider string) (*model.ProviderToken, error)
+		DeleteProviderToken(userId string, provider string) error
+		AddSocialLoginState(stateHash string, state *model.SocialLoginState, ttl time.Duration) error
+		TakeSocialLoginState(stateHash string) (*model.SocialLoginState, error)
 		FindUserByUID(objectID primitive.ObjectID) (*model.User, error)
 	}
 
@@ -44,24 +91,12 @@ func (r *authRepository) userCollection() *mongo.Collection {
 	return r.db.Database("Auth").Collection("Users")
 }
 
-func (r *authRepository) blacklistCollection() *mongo.Collection {
-	return r.db.Database("Auth").Collection("Blacklists")
+func (r *authRepository) refreshTokenCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("RefreshTokens")
 }
 
-func (r *authRepository) FindByProviderId(id string) (*model.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	collection := r.userCollection()
-
-	var user model.User
-	err := collection.FindOne(ctx, bson.M{"oauth_id": id}).Decode(&user)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, err
-
+func (r *authRepository) securityEventCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("SecurityEvents")
 }
 
 func (r *authRepository) AddUser(userPassport *model.UserPassport) (*model.User, error) {
@@ -69,16 +104,25 @@ func (r *authRepository) AddUser(userPassport *model.UserPassport) (*model.User,
 	defer cancel()
 
 	newUser := &model.User{
+		ID:            primitive.NewObjectID(),
 		Email:         userPassport.Email,
 		Password:      userPassport.Password,
 		OauthProvider: userPassport.OauthProvider,
 		OauthId:       userPassport.OauthId,
+		Role:          userPassport.Role,
+		EmailVerified: userPassport.EmailVerified,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
 
 	if userPassport.OauthId != "" {
 		newUser.OauthId = userPassport.OauthId
+		newUser.Identities = []model.Identity{{
+			Provider: userPassport.OauthProvider,
+			Subject:  userPassport.OauthId,
+			Email:    userPassport.Email,
+			LinkedAt: newUser.CreatedAt,
+		}}
 	}
 
 	collection := r.userCollection()
@@ -122,62 +166,152 @@ func (r *authRepository) FindUserByUID(objectID primitive.ObjectID) (*model.User
 	return user, err
 }
 
-func (r *authRepository) AddBlacklistToken(refreshToken string, expiration time.Time) error {
+func (r *authRepository) AccessToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error) {
+	return jwtAuth.NewAccessToken(cfg.Jwt.AccessTokenSecret, cfg.Jwt.AccessTokenDuration, &jwtAuth.Claims{
+		UserId:        claims.UserId,
+		RoleCode:      claims.RoleCode,
+		EmailVerified: claims.EmailVerified,
+		FamilyId:      claims.FamilyId,
+		ClientId:      claims.ClientId,
+		Scope:         claims.Scope,
+		TokenVersion:  claims.TokenVersion,
+	}).SignToken()
+}
+
+// RefreshToken signs a new refresh token in the family of claims and records its jti
+// so that the token can later be rotated or revoked with the rest of its family.
+func (r *authRepository) RefreshToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	collection := r.blacklistCollection()
+	factory := jwtAuth.NewRefreshToken(cfg.Jwt.RefreshTokenSecret, cfg.Jwt.RefreshTokenDuration, &jwtAuth.Claims{
+		UserId:        claims.UserId,
+		RoleCode:      claims.RoleCode,
+		EmailVerified: claims.EmailVerified,
+		FamilyId:      claims.FamilyId,
+		ClientId:      claims.ClientId,
+		Scope:         claims.Scope,
+		TokenVersion:  claims.TokenVersion,
+	})
+
+	signed, err := factory.SignToken()
+	if err != nil {
+		return "", err
+	}
+	tokenClaims := factory.GetClaims()
+
+	record := &model.RefreshTokenRecord{
+		Jti:       tokenClaims.ID,
+		FamilyId:  claims.FamilyId,
+		UserId:    claims.UserId,
+		ExpiresAt: tokenClaims.ExpiresAt.Time,
+		CreatedAt: time.Now(),
+	}
 
-	blacklist := bson.M{
-		"refresh_token": refreshToken,
-		"expires_at":    expiration,
+	if _, err := r.refreshTokenCollection().InsertOne(ctx, record); err != nil {
+		return "", err
 	}
 
-	_, err := collection.InsertOne(ctx, blacklist)
+	return signed, nil
+}
 
-	duration := time.Until(expiration)
-	if err := r.redis.Set(ctx, refreshToken, "blacklisted", duration).Err(); err != nil {
-		return err
+func (r *authRepository) FindRefreshToken(jti string) (*model.RefreshTokenRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var record model.RefreshTokenRecord
+	err := r.refreshTokenCollection().FindOne(ctx, bson.M{"jti": jti}).Decode(&record)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return &record, nil
 }
 
-func (r *authRepository) IsBlacklistExist(refreshToken string) (bool, error) {
+// RotateRefreshToken marks the refresh token as used. It reports false when the token
+// had already been rotated or revoked, which means it is being replayed.
+func (r *authRepository) RotateRefreshToken(jti string) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result, err := r.redis.Get(ctx, refreshToken).Result()
-	if err == redis.Nil {
-		collection := r.blacklistCollection()
-		filter := bson.M{
-			"refresh_token": refreshToken,
-			"expires_at":    bson.M{"$gt": time.Now()},
-		}
-
-		count, err := collection.CountDocuments(ctx, filter)
-		if err != nil {
-			return false, err
-		}
-
-		return count > 0, nil
-	} else if err != nil {
+	filter := bson.M{
+		"jti":        jti,
+		"revoked":    false,
+		"rotated_at": nil,
+	}
+	update := bson.M{"$set": bson.M{"rotated_at": time.Now()}}
+
+	result, err := r.refreshTokenCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
 		return false, err
 	}
-	return result == "blacklisted", nil
 
+	return result.ModifiedCount == 1, nil
 }
 
-func (r *authRepository) AccessToken(cfg *config.Config, claims *jwtAuth.Claims) string {
-	return jwtAuth.NewAccessToken(cfg.Jwt.AccessTokenSecret, cfg.Jwt.AccessTokenDuration, &jwtAuth.Claims{
-		UserId:   claims.UserId,
-		RoleCode: claims.RoleCode,
-	}).SignToken()
+func (r *authRepository) RevokeRefreshTokenFamily(familyId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"family_id": familyId}
+	update := bson.M{"$set": bson.M{"revoked": true}}
+
+	_, err := r.refreshTokenCollection().UpdateMany(ctx, filter, update)
+	return err
 }
 
-func (r *authRepository) RefreshToken(cfg *config.Config, claims *jwtAuth.Claims) string {
-	return jwtAuth.NewRefreshToken(cfg.Jwt.RefreshTokenSecret, cfg.Jwt.RefreshTokenDuration, &jwtAuth.Claims{
-		UserId:   claims.UserId,
-		RoleCode: claims.RoleCode,
-	}).SignToken()
+func (r *authRepository) AddSecurityEvent(event *model.SecurityEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	_, err := r.securityEventCollection().InsertOne(ctx, event)
+	return err
+}
+
+func (r *authRepository) UpdateUserRole(objectID primitive.ObjectID, role string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{"$set": bson.M{
+		"role":       role,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *authRepository) SetUserDisabled(objectID primitive.ObjectID, disabled bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{"$set": bson.M{
+		"disabled":   disabled,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrUserNotFound
+	}
+
+	return nil
 }
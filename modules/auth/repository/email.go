@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (r *authRepository) emailVerificationCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("EmailVerifications")
+}
+
+func (r *authRepository) AddEmailVerificationToken(token *model.EmailVerificationToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.emailVerificationCollection()
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": token.UserId, "used_at": nil}); err != nil {
+		return err
+	}
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// ConsumeEmailVerificationToken marks an unexpired, unused token as used and returns it.
+func (r *authRepository) ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	var token model.EmailVerificationToken
+	err := r.emailVerificationCollection().FindOneAndUpdate(ctx, filter, update).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrInvalidVerificationToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// MarkEmailVerified flags the email of the user as verified, as long as it is still
+// the address the verification link was sent to.
+func (r *authRepository) MarkEmailVerified(objectID primitive.ObjectID, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID, "email": email}
+	update := bson.M{"$set": bson.M{
+		"email_verified": true,
+		"updated_at":     time.Now(),
+	}}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// AllowEmailVerificationResend reports whether another verification email may be sent
+// to the address, allowing at most one per interval.
+func (r *authRepository) AllowEmailVerificationResend(email string, interval time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.redis.SetNX(ctx, "email_verification_resend:"+email, 1, interval).Result()
+}
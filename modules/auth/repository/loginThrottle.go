@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+)
+
+func loginFailuresKey(subject string) string {
+	return "login_failures:" + subject
+}
+
+func loginLockKey(subject string) string {
+	return "login_lock:" + subject
+}
+
+func loginDelayKey(subject string) string {
+	return "login_delay:" + subject
+}
+
+// AddLoginFailure counts a failed login of the subject and returns the failures so far.
+// The count starts over once window has passed since the first of them.
+func (r *authRepository) AddLoginFailure(subject string, window time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := loginFailuresKey(subject)
+
+	failures, err := r.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if failures == 1 {
+		if err := r.redis.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return failures, nil
+}
+
+func (r *authRepository) BlockLogin(subject string, block *model.LoginBlock) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := loginDelayKey(subject)
+	if block.Locked {
+		key = loginLockKey(subject)
+	}
+
+	return r.redis.Set(ctx, key, 1, block.RetryAfter).Err()
+}
+
+// FindLoginBlock returns the block on the subject, a lock before a delay, or nil when
+// it may try to log in.
+func (r *authRepository) FindLoginBlock(subject string) (*model.LoginBlock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipe := r.redis.Pipeline()
+	lock := pipe.PTTL(ctx, loginLockKey(subject))
+	delay := pipe.PTTL(ctx, loginDelayKey(subject))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	// PTTL is negative for a missing key
+	if ttl := lock.Val(); ttl > 0 {
+		return &model.LoginBlock{Locked: true, RetryAfter: ttl}, nil
+	}
+	if ttl := delay.Val(); ttl > 0 {
+		return &model.LoginBlock{RetryAfter: ttl}, nil
+	}
+
+	return nil, nil
+}
+
+// ResetLoginFailures forgets the failures of the subject and lifts any block on it.
+func (r *authRepository) ResetLoginFailures(subject string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.redis.Del(ctx, loginFailuresKey(subject), loginLockKey(subject), loginDelayKey(subject)).Err()
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func mfaChallengeKey(tokenHash string) string {
+	return "mfa_challenge:" + tokenHash
+}
+
+func (r *authRepository) SetPendingTotpSecret(objectID primitive.ObjectID, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{"$set": bson.M{
+		"pending_totp_secret": secret,
+		"updated_at":          time.Now(),
+	}}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+// EnableMfa promotes the pending secret of the user and stores the hashed recovery codes.
+func (r *authRepository) EnableMfa(objectID primitive.ObjectID, secret string, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{
+		"$set": bson.M{
+			"mfa_enabled":    true,
+			"totp_secret":    secret,
+			"recovery_codes": recoveryCodeHashes,
+			"updated_at":     time.Now(),
+		},
+		"$unset": bson.M{"pending_totp_secret": ""},
+	}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *authRepository) DisableMfa(objectID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{
+		"$set": bson.M{
+			"mfa_enabled": false,
+			"updated_at":  time.Now(),
+		},
+		"$unset": bson.M{
+			"totp_secret":         "",
+			"pending_totp_secret": "",
+			"recovery_codes":      "",
+		},
+	}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *authRepository) SetRecoveryCodes(objectID primitive.ObjectID, recoveryCodeHashes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{"$set": bson.M{
+		"recovery_codes": recoveryCodeHashes,
+		"updated_at":     time.Now(),
+	}}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+// ConsumeRecoveryCode removes the recovery code from the user, reporting false when
+// the user does not have it.
+func (r *authRepository) ConsumeRecoveryCode(objectID primitive.ObjectID, recoveryCodeHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID, "recovery_codes": recoveryCodeHash}
+	update := bson.M{"$pull": bson.M{"recovery_codes": recoveryCodeHash}}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// MarkTotpStepUsed records that the user spent the code of a time step, reporting false
+// when it was already used.
+func (r *authRepository) MarkTotpStepUsed(userId string, step int64, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.redis.SetNX(ctx, "totp_used:"+userId+":"+strconv.FormatInt(step, 10), 1, ttl).Result()
+}
+
+func (r *authRepository) AddMfaChallenge(tokenHash string, userId string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := mfaChallengeKey(tokenHash)
+
+	pipe := r.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userId, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// FindMfaChallenge returns the user of the challenge and counts one more attempt on it.
+// The challenge is dropped once it runs out of attempts.
+func (r *authRepository) FindMfaChallenge(tokenHash string, maxAttempts int64) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	key := mfaChallengeKey(tokenHash)
+
+	userId, err := r.redis.HGet(ctx, key, "user_id").Result()
+	if err == redis.Nil {
+		return "", model.ErrInvalidMfaToken
+	} else if err != nil {
+		return "", err
+	}
+
+	attempts, err := r.redis.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return "", err
+	}
+
+	if attempts > maxAttempts {
+		r.redis.Del(ctx, key)
+		return "", model.ErrInvalidMfaToken
+	}
+
+	return userId, nil
+}
+
+func (r *authRepository) DeleteMfaChallenge(tokenHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.redis.Del(ctx, mfaChallengeKey(tokenHash)).Err()
+}
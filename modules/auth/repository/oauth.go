@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	OAuthRepository interface {
+		clientCollection() *mongo.Collection
+		consentCollection() *mongo.Collection
+		serviceAccountCollection() *mongo.Collection
+		AddClient(client *model.OAuthClient) error
+		FindClient(clientId string) (*model.OAuthClient, error)
+		FindClients() ([]*model.OAuthClient, error)
+		DisableClient(clientId string) error
+		FindConsent(userId string, clientId string) (*model.OAuthConsent, error)
+		SaveConsent(userId string, clientId string, scopes []string) error
+		AddAuthorizationCode(codeHash string, code *model.AuthorizationCode, ttl time.Duration) error
+		TakeAuthorizationCode(codeHash string) (*model.AuthorizationCode, error)
+		AddServiceAccount(account *model.ServiceAccount) error
+		FindServiceAccount(clientId string) (*model.ServiceAccount, error)
+		FindServiceAccounts() ([]*model.ServiceAccount, error)
+		RotateServiceAccountSecret(clientId string, secretHash string, previousSecretExpiresAt time.Time) (*model.ServiceAccount, error)
+		DisableServiceAccount(clientId string) error
+	}
+
+	oauthRepository struct {
+		db    *mongo.Client
+		redis *redis.Client
+	}
+)
+
+func NewOAuthRepository(db *mongo.Client, redis *redis.Client) OAuthRepository {
+	return &oauthRepository{
+		db,
+		redis,
+	}
+}
+
+func (r *oauthRepository) clientCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("OAuthClients")
+}
+
+func (r *oauthRepository) consentCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("OAuthConsents")
+}
+
+func authorizationCodeKey(codeHash string) string {
+	return "oauth_code:" + codeHash
+}
+
+func (r *oauthRepository) AddClient(client *model.OAuthClient) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.clientCollection().InsertOne(ctx, client)
+	return err
+}
+
+func (r *oauthRepository) FindClient(clientId string) (*model.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var client model.OAuthClient
+	err := r.clientCollection().FindOne(ctx, bson.M{"client_id": clientId}).Decode(&client)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrOAuthClientNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func (r *oauthRepository) FindClients() ([]*model.OAuthClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.clientCollection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	clients := make([]*model.OAuthClient, 0)
+	if err := cursor.All(ctx, &clients); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (r *oauthRepository) DisableClient(clientId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"client_id": clientId}
+	update := bson.M{"$set": bson.M{
+		"disabled":   true,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := r.clientCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrOAuthClientNotFound
+	}
+
+	return nil
+}
+
+func (r *oauthRepository) FindConsent(userId string, clientId string) (*model.OAuthConsent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var consent model.OAuthConsent
+	err := r.consentCollection().FindOne(ctx, bson.M{"user_id": userId, "client_id": clientId}).Decode(&consent)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &consent, nil
+}
+
+// SaveConsent adds the scopes to what the user already granted the client.
+func (r *oauthRepository) SaveConsent(userId string, clientId string, scopes []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "client_id": clientId}
+	update := bson.M{
+		"$addToSet":    bson.M{"scopes": bson.M{"$each": scopes}},
+		"$set":         bson.M{"updated_at": time.Now()},
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}
+
+	_, err := r.consentCollection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *oauthRepository) AddAuthorizationCode(codeHash string, code *model.AuthorizationCode, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(code)
+	if err != nil {
+		return err
+	}
+
+	return r.redis.Set(ctx, authorizationCodeKey(codeHash), data, ttl).Err()
+}
+
+// TakeAuthorizationCode returns the code and deletes it in the same step, so a code
+// can only ever be redeemed once.
+func (r *oauthRepository) TakeAuthorizationCode(codeHash string) (*model.AuthorizationCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := r.redis.GetDel(ctx, authorizationCodeKey(codeHash)).Bytes()
+	if err == redis.Nil {
+		return nil, model.ErrOAuthInvalidGrant("Invalid authorization code")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var code model.AuthorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (r *authRepository) passwordResetCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("PasswordResets")
+}
+
+// AddPasswordResetToken stores a reset token and drops any earlier unused token of the user,
+// so only the latest emailed link works.
+func (r *authRepository) AddPasswordResetToken(token *model.PasswordResetToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := r.passwordResetCollection()
+
+	if _, err := collection.DeleteMany(ctx, bson.M{"user_id": token.UserId, "used_at": nil}); err != nil {
+		return err
+	}
+
+	_, err := collection.InsertOne(ctx, token)
+	return err
+}
+
+// FindPasswordResetToken returns an unexpired, unused token without using it up.
+func (r *authRepository) FindPasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var token model.PasswordResetToken
+	err := r.passwordResetCollection().FindOne(ctx, filter).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrInvalidResetToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ConsumePasswordResetToken marks an unexpired, unused token as used and returns it.
+func (r *authRepository) ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    nil,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	var token model.PasswordResetToken
+	err := r.passwordResetCollection().FindOneAndUpdate(ctx, filter, update).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrInvalidResetToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UpdateUserPassword sets the new password and moves the current one to the front of
+// the password history, which keeps the historySize latest passwords counting the new one.
+func (r *authRepository) UpdateUserPassword(objectID primitive.ObjectID, hashedPassword string, historySize int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"password":   hashedPassword,
+		"updated_at": time.Now(),
+	}
+
+	if historySize > 1 {
+		// Accounts of a social login have no password to keep
+		previous := bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$password", ""}},
+			bson.A{"$password"},
+			bson.A{},
+		}}
+		set["password_history"] = bson.M{"$slice": bson.A{
+			bson.M{"$concatArrays": bson.A{previous, bson.M{"$ifNull": bson.A{"$password_history", bson.A{}}}}},
+			historySize - 1,
+		}}
+	}
+
+	filter := bson.M{"_id": objectID}
+	update := mongo.Pipeline{{{Key: "$set", Value: set}}}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RehashUserPassword swaps the hash of the same password for a stronger one, unless the
+// password was changed in the meantime.
+func (r *authRepository) RehashUserPassword(objectID primitive.ObjectID, oldHash string, newHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID, "password": oldHash}
+	update := bson.M{"$set": bson.M{"password": newHash}}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *authRepository) RevokeRefreshTokensByUserId(userId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId}
+	update := bson.M{"$set": bson.M{"revoked": true}}
+
+	_, err := r.refreshTokenCollection().UpdateMany(ctx, filter, update)
+	return err
+}
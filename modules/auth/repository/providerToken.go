@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *authRepository) providerTokenCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("ProviderTokens")
+}
+
+// SaveProviderToken keeps the latest token of the provider for the user, replacing the
+// one before
+func (r *authRepository) SaveProviderToken(token *model.ProviderToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": token.UserId, "provider": token.Provider}
+
+	_, err := r.providerTokenCollection().ReplaceOne(ctx, filter, token, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *authRepository) FindProviderToken(userId string, provider string) (*model.ProviderToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "provider": provider}
+
+	var token model.ProviderToken
+	err := r.providerTokenCollection().FindOne(ctx, filter).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrProviderTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *authRepository) DeleteProviderToken(userId string, provider string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.providerTokenCollection().DeleteOne(ctx, bson.M{"user_id": userId, "provider": provider})
+	return err
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *oauthRepository) serviceAccountCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("ServiceAccounts")
+}
+
+func (r *oauthRepository) AddServiceAccount(account *model.ServiceAccount) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.serviceAccountCollection().InsertOne(ctx, account)
+	return err
+}
+
+func (r *oauthRepository) FindServiceAccount(clientId string) (*model.ServiceAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var account model.ServiceAccount
+	err := r.serviceAccountCollection().FindOne(ctx, bson.M{"client_id": clientId}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrServiceAccountNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (r *oauthRepository) FindServiceAccounts() ([]*model.ServiceAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.serviceAccountCollection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	accounts := make([]*model.ServiceAccount, 0)
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// RotateServiceAccountSecret replaces the secret of the account. The current secret is
+// kept as the previous one until previousSecretExpiresAt, so running jobs can switch over.
+func (r *oauthRepository) RotateServiceAccountSecret(clientId string, secretHash string, previousSecretExpiresAt time.Time) (*model.ServiceAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"client_id": clientId}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"previous_secret_hash":       "$secret_hash",
+			"previous_secret_expires_at": previousSecretExpiresAt,
+			"secret_hash":                secretHash,
+			"secret_rotated_at":          time.Now(),
+			"updated_at":                 time.Now(),
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var account model.ServiceAccount
+	err := r.serviceAccountCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrServiceAccountNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func (r *oauthRepository) DisableServiceAccount(clientId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"client_id": clientId}
+	update := bson.M{"$set": bson.M{
+		"disabled":   true,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := r.serviceAccountCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrServiceAccountNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type (
+	SessionRepository interface {
+		sessionCollection() *mongo.Collection
+		AddSession(session *model.Session) error
+		FindSession(sessionId string) (*model.Session, error)
+		FindActiveSessionsByUserId(userId string) ([]*model.Session, error)
+		TouchSession(sessionId string, ipAddress string, expiresAt time.Time) (*model.Session, error)
+		RevokeSession(sessionId string) error
+		RevokeSessionsByUserId(userId string) error
+	}
+
+	sessionRepository struct {
+		db    *mongo.Client
+		redis *redis.Client
+	}
+)
+
+func NewSessionRepository(db *mongo.Client, redis *redis.Client) SessionRepository {
+	return &sessionRepository{
+		db,
+		redis,
+	}
+}
+
+func (r *sessionRepository) sessionCollection() *mongo.Collection {
+	return r.db.Database("Auth").Collection("Sessions")
+}
+
+func sessionCacheKey(sessionId string) string {
+	return "session:" + sessionId
+}
+
+func (r *sessionRepository) cacheSession(ctx context.Context, session *model.Session) {
+	data, err := bson.Marshal(session)
+	if err != nil {
+		return
+	}
+
+	r.redis.Set(ctx, sessionCacheKey(session.ID), data, time.Until(session.ExpiresAt))
+}
+
+func (r *sessionRepository) cachedSession(ctx context.Context, sessionId string) *model.Session {
+	data, err := r.redis.Get(ctx, sessionCacheKey(sessionId)).Bytes()
+	if err != nil {
+		return nil
+	}
+
+	var session model.Session
+	if err := bson.Unmarshal(data, &session); err != nil {
+		return nil
+	}
+
+	return &session
+}
+
+func (r *sessionRepository) AddSession(session *model.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := r.sessionCollection().InsertOne(ctx, session); err != nil {
+		return err
+	}
+
+	r.cacheSession(ctx, session)
+
+	return nil
+}
+
+// FindSession returns the session if it exists and has not been revoked.
+func (r *sessionRepository) FindSession(sessionId string) (*model.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if session := r.cachedSession(ctx, sessionId); session != nil {
+		return session, nil
+	}
+
+	var session model.Session
+	filter := bson.M{
+		"_id":        sessionId,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	err := r.sessionCollection().FindOne(ctx, filter).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	r.cacheSession(ctx, &session)
+
+	return &session, nil
+}
+
+func (r *sessionRepository) FindActiveSessionsByUserId(userId string) ([]*model.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":    userId,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "last_used_at", Value: -1}})
+
+	cursor, err := r.sessionCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := make([]*model.Session, 0)
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) TouchSession(sessionId string, ipAddress string, expiresAt time.Time) (*model.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": sessionId, "revoked": false}
+	update := bson.M{"$set": bson.M{
+		"ip_address":   ipAddress,
+		"last_used_at": time.Now(),
+		"expires_at":   expiresAt,
+	}}
+
+	var session model.Session
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.sessionCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, model.ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	r.cacheSession(ctx, &session)
+
+	return &session, nil
+}
+
+func (r *sessionRepository) RevokeSession(sessionId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": sessionId}
+	update := bson.M{"$set": bson.M{"revoked": true}}
+
+	if _, err := r.sessionCollection().UpdateOne(ctx, filter, update); err != nil {
+		return err
+	}
+
+	return r.redis.Del(ctx, sessionCacheKey(sessionId)).Err()
+}
+
+func (r *sessionRepository) RevokeSessionsByUserId(userId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userId, "revoked": false}
+
+	ids, err := r.sessionCollection().Distinct(ctx, "_id", filter)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.sessionCollection().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}}); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if sessionId, ok := id.(string); ok {
+			keys = append(keys, sessionCacheKey(sessionId))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.redis.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func socialLoginStateKey(stateHash string) string {
+	return "social_login:" + stateHash
+}
+
+// AddSocialLoginState keeps the state of a social login until the provider redirects back.
+func (r *authRepository) AddSocialLoginState(stateHash string, state *model.SocialLoginState, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return r.redis.Set(ctx, socialLoginStateKey(stateHash), data, ttl).Err()
+}
+
+// TakeSocialLoginState returns the state of a social login and removes it, so a callback
+// can only be used once.
+func (r *authRepository) TakeSocialLoginState(stateHash string) (*model.SocialLoginState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := r.redis.GetDel(ctx, socialLoginStateKey(stateHash)).Bytes()
+	if err == redis.Nil {
+		return nil, model.ErrInvalidSocialLoginState
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state model.SocialLoginState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// FindByProviderId returns the user with the identity of the provider
+func (r *authRepository) FindByProviderId(provider string, id string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": id}}}
+
+	var user model.User
+	if err := r.userCollection().FindOne(ctx, filter).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// AddUserIdentity links the identity to the user, which may have one identity per
+// provider. An identity linked to another user fails on the unique index.
+func (r *authRepository) AddUserIdentity(objectID primitive.ObjectID, identity *model.Identity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":                 objectID,
+		"identities.provider": bson.M{"$ne": identity.Provider},
+	}
+	update := bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return model.ErrIdentityAlreadyLinked
+	} else if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return model.ErrIdentityAlreadyLinked
+	}
+
+	return nil
+}
+
+// RemoveUserIdentity unlinks the identity of the provider, unless it is the only way left
+// to log in. Reports whether it was removed.
+func (r *authRepository) RemoveUserIdentity(objectID primitive.ObjectID, provider string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Checked in the filter, so two unlinks at once cannot both pass
+	filter := bson.M{
+		"_id":                 objectID,
+		"identities.provider": provider,
+		"$or": bson.A{
+			bson.M{"password": bson.M{"$gt": ""}},
+			bson.M{"passkeys.0": bson.M{"$exists": true}},
+			bson.M{"identities.1": bson.M{"$exists": true}},
+		},
+	}
+	update := bson.M{
+		"$pull": bson.M{"identities": bson.M{"provider": provider}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.userCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	// The account no longer signs in with the provider it was registered with
+	legacyFilter := bson.M{"_id": objectID, "oauth_provider": provider}
+	if _, err := r.userCollection().UpdateOne(ctx, legacyFilter, bson.M{"$set": bson.M{"oauth_id": ""}}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"go-auth/modules/auth/model"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (r *authRepository) AddPasskey(objectID primitive.ObjectID, passkey *model.Passkey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{
+		"$push": bson.M{"passkeys": passkey},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (r *authRepository) UpdatePasskeySignCount(objectID primitive.ObjectID, credentialId []byte, signCount uint32) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID, "passkeys.credential_id": credentialId}
+	update := bson.M{"$set": bson.M{
+		"passkeys.$.sign_count":   signCount,
+		"passkeys.$.last_used_at": time.Now(),
+	}}
+
+	_, err := r.userCollection().UpdateOne(ctx, filter, update)
+	return err
+}
+
+// SaveWebauthnSession keeps the state of a WebAuthn ceremony until the browser answers.
+func (r *authRepository) SaveWebauthnSession(key string, data []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return r.redis.Set(ctx, "webauthn_session:"+key, data, ttl).Err()
+}
+
+// TakeWebauthnSession returns the state of a ceremony and removes it, so each challenge is
+// answered at most once.
+func (r *authRepository) TakeWebauthnSession(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := r.redis.GetDel(ctx, "webauthn_session:"+key).Bytes()
+	if err == redis.Nil {
+		return nil, model.ErrInvalidWebauthnSession
+	}
+
+	return data, err
+}
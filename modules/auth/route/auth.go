@@ -5,26 +5,110 @@ import (
 	"go-auth/modules/auth/handler"
 	"go-auth/modules/auth/repository"
 	"go-auth/modules/auth/useCase"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/mailer"
+	"go-auth/pkg/oauth"
+	"go-auth/pkg/passwordHasher"
+	"go-auth/pkg/passwordPolicy"
+	"go-auth/pkg/tokenVault"
+	"go-auth/pkg/webauthnService"
 	"go-auth/server/types"
+	"time"
+
+	"github.com/labstack/echo/v4"
 )
 
 func AuthRoute(s *types.Server) {
 
-	authRepo := repository.NewAuthRepository(s.Db)
-	authUsecase := useCase.NewAuthUsecase(authRepo)
+	authRepo := repository.NewAuthRepository(s.Db, s.Redis)
+	sessionRepo := repository.NewSessionRepository(s.Db, s.Redis)
+	oauthRepo := repository.NewOAuthRepository(s.Db, s.Redis)
+	authUsecase := useCase.NewAuthUsecase(authRepo, sessionRepo, oauthRepo, s.Revocation, mailer.NewMailer(s.Cfg), webauthnService.NewWebAuthn(s.Cfg), passwordHasher.NewPasswordHasher(s.Cfg), passwordPolicy.NewPasswordPolicy(s.Cfg), oauth.NewFromConfig(s.Cfg), tokenVault.NewTokenVault(s.Cfg))
 	authHandler := handler.NewAuthHandler(authUsecase, s.Cfg)
 
-	// oauthHandler := oauth.NewOAuthHandler(s.Cfg, authUsecase)
+	apiKeyMiddleware := middleware.JWTOrApiKeyMiddleware(func(c echo.Context, key string) (*jwtAuth.AuthMapClaims, error) {
+		return authUsecase.AuthenticateApiKey(s.Cfg, key, c.RealIP())
+	})
 
-	s.App.POST("/auth/register/email", authHandler.RegisterByEmail)
-	s.App.POST("/auth/login", authHandler.Login)
-	s.App.POST("/auth/logout", authHandler.Logout)
-	s.App.POST("/auth/refreshToken", authHandler.RefreshToken, middleware.JWTMiddleware())
-	s.App.GET("/auth/users", authHandler.FindUserByUID, middleware.JWTMiddleware())
-	s.App.GET("/auth/facebook/login", authHandler.FacebookLogin)
-	s.App.GET("/auth/facebook/callback", authHandler.FacebookCallback)
+	rateLimit := func(name string, limit int64, window time.Duration, keyFunc middleware.RateLimitKeyFunc) echo.MiddlewareFunc {
+		return middleware.RateLimit(s.RateLimiter, middleware.RateLimitConfig{
+			Name:    name,
+			Limit:   limit,
+			Window:  window,
+			KeyFunc: keyFunc,
+		})
+	}
+
+	// Login attempts are limited per IP here, and per account by the lockout of the usecase
+	loginLimit := rateLimit("login", 20, time.Minute, middleware.KeyByIp)
+	apiLimit := rateLimit("api", 120, time.Minute, middleware.KeyByUserId)
+	// Routes open to personal API keys count each key on its own
+	apiKeyLimit := rateLimit("api", 120, time.Minute, middleware.KeyByApiKey)
+	// The OAuth endpoints authenticate the client in the handler, after the limit, so
+	// they are counted by IP
+	oauthLimit := rateLimit("oauth", 60, time.Minute, middleware.KeyByIp)
+
+	// Third-party clients may read the profile of their user with a token granted openid
+	userInfoMiddleware := middleware.JWTOrApiKeyMiddleware(func(c echo.Context, key string) (*jwtAuth.AuthMapClaims, error) {
+		return authUsecase.AuthenticateApiKey(s.Cfg, key, c.RealIP())
+	}, jwtAuth.AudienceClient)
 
-	s.App.GET("/auth/google/login", authHandler.FacebookLogin)
-	s.App.GET("/auth/google/callback", authHandler.FacebookCallback)
+	s.App.GET("/.well-known/jwks.json", authHandler.JWKS)
+	s.App.GET("/.well-known/openid-configuration", authHandler.OpenIdConfiguration)
+	s.App.GET("/userinfo", authHandler.UserInfo, userInfoMiddleware, middleware.RequireScope("openid"), apiKeyLimit)
+	s.App.POST("/userinfo", authHandler.UserInfo, userInfoMiddleware, middleware.RequireScope("openid"), apiKeyLimit)
 
+	// The login UI calls these for the browser, see OIDC_AUTHORIZATION_ENDPOINT
+	s.App.GET("/oauth/authorize", authHandler.Authorize, middleware.JWTMiddleware())
+	s.App.POST("/oauth/authorize", authHandler.Authorize, middleware.JWTMiddleware())
+	s.App.POST("/oauth/token", authHandler.Token, oauthLimit)
+	s.App.POST("/oauth/introspect", authHandler.Introspect, rateLimit("introspect", 600, time.Minute, middleware.KeyByIp))
+	s.App.POST("/oauth/revoke", authHandler.RevokeToken, oauthLimit)
+
+	s.App.GET("/internal/users/:id/provider-tokens/:provider", authHandler.ProviderToken, middleware.JWTMiddleware(jwtAuth.AudienceService), middleware.RequireServiceScope("provider_tokens:read"), rateLimit("provider_token", 600, time.Minute, middleware.KeyByClientId))
+
+	s.App.POST("/admin/oauth/clients", authHandler.CreateOAuthClient, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.GET("/admin/oauth/clients", authHandler.ListOAuthClients, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.DELETE("/admin/oauth/clients/:id", authHandler.DisableOAuthClient, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.POST("/admin/service-accounts", authHandler.CreateServiceAccount, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.GET("/admin/service-accounts", authHandler.ListServiceAccounts, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.POST("/admin/service-accounts/:id/rotate", authHandler.RotateServiceAccountSecret, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.DELETE("/admin/service-accounts/:id", authHandler.DisableServiceAccount, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.PUT("/admin/users/:id/role", authHandler.UpdateUserRole, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.POST("/admin/users/:id/unlock", authHandler.UnlockUser, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.POST("/admin/users/:id/disable", authHandler.DisableUser, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+	s.App.POST("/admin/users/:id/enable", authHandler.EnableUser, middleware.JWTMiddleware(), middleware.RequireRole("admin"))
+
+	s.App.POST("/auth/register/email", authHandler.RegisterByEmail, rateLimit("register", 5, time.Hour, middleware.KeyByIp))
+	s.App.POST("/auth/login", authHandler.Login, loginLimit)
+	s.App.POST("/auth/login/mfa", authHandler.LoginMfa, loginLimit)
+	s.App.POST("/auth/webauthn/login/begin", authHandler.BeginPasskeyLogin, loginLimit)
+	s.App.POST("/auth/webauthn/login/finish", authHandler.FinishPasskeyLogin, loginLimit)
+	s.App.POST("/auth/logout", authHandler.Logout)
+	s.App.POST("/auth/password/forgot", authHandler.ForgotPassword, rateLimit("password_forgot", 5, 15*time.Minute, middleware.KeyByIp))
+	s.App.POST("/auth/password/reset", authHandler.ResetPassword, rateLimit("password_reset", 10, 15*time.Minute, middleware.KeyByIp))
+	s.App.POST("/auth/password/change", authHandler.ChangePassword, middleware.JWTMiddleware(), rateLimit("password_change", 10, 15*time.Minute, middleware.KeyByUserId))
+	s.App.POST("/auth/email/verify", authHandler.VerifyEmail, rateLimit("email_verify", 20, 15*time.Minute, middleware.KeyByIp))
+	s.App.POST("/auth/email/resend", authHandler.ResendVerificationEmail, rateLimit("email_resend", 5, 15*time.Minute, middleware.KeyByIp))
+	s.App.POST("/auth/refreshToken", authHandler.RefreshToken, rateLimit("refresh", 30, time.Minute, middleware.KeyByIp))
+	s.App.GET("/auth/users", authHandler.FindUserByUID, apiKeyMiddleware, middleware.RequireScope("users:read"), apiKeyLimit)
+	s.App.GET("/auth/sessions", authHandler.ListSessions, middleware.JWTMiddleware())
+	s.App.DELETE("/auth/sessions", authHandler.RevokeOtherSessions, middleware.JWTMiddleware())
+	s.App.DELETE("/auth/sessions/:id", authHandler.RevokeSession, middleware.JWTMiddleware())
+	s.App.POST("/auth/mfa/totp/enroll", authHandler.EnrollTotp, middleware.JWTMiddleware())
+	s.App.POST("/auth/mfa/totp/confirm", authHandler.ConfirmTotp, middleware.JWTMiddleware())
+	s.App.POST("/auth/mfa/disable", authHandler.DisableMfa, middleware.JWTMiddleware())
+	s.App.POST("/auth/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes, middleware.JWTMiddleware())
+	s.App.POST("/auth/webauthn/register/begin", authHandler.BeginPasskeyRegistration, middleware.JWTMiddleware())
+	s.App.POST("/auth/webauthn/register/finish", authHandler.FinishPasskeyRegistration, middleware.JWTMiddleware())
+	s.App.POST("/auth/api-keys", authHandler.CreateApiKey, middleware.JWTMiddleware())
+	s.App.GET("/auth/api-keys", authHandler.ListApiKeys, middleware.JWTMiddleware())
+	s.App.DELETE("/auth/api-keys/:id", authHandler.RevokeApiKey, middleware.JWTMiddleware())
+	s.App.GET("/auth/identities", authHandler.ListIdentities, middleware.JWTMiddleware())
+	s.App.POST("/auth/identities/:provider", authHandler.LinkIdentity, middleware.JWTMiddleware(), apiLimit)
+	s.App.DELETE("/auth/identities/:provider", authHandler.UnlinkIdentity, middleware.JWTMiddleware())
+	s.App.GET("/auth/:provider/login", authHandler.SocialLogin, loginLimit)
+	s.App.GET("/auth/:provider/callback", authHandler.SocialCallback, loginLimit)
+	s.App.POST("/auth/:provider/callback", authHandler.SocialCallback, loginLimit)
+	s.App.POST("/auth/:provider/id-token", authHandler.SocialIdTokenLogin, loginLimit)
 }
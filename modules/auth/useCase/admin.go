@@ -0,0 +1,84 @@
+package useCase
+
+import (
+	"go-auth/modules/auth/model"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdateUserRole changes the role of the user. Tokens issued with the old role stop
+// working right away, the user picks up the new one on the next refresh.
+func (u *authUsecase) UpdateUserRole(userId string, updateReq *model.UpdateRoleReq) error {
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.ErrUserNotFound
+	}
+
+	if err := u.authRepository.UpdateUserRole(objectID, updateReq.Role); err != nil {
+		return err
+	}
+
+	_, err = u.revocation.BumpTokenVersion(userId)
+	return err
+}
+
+// DisableUser locks the user out for good: every session ends, every access token stops
+// working at once and no new ones are issued until the user is enabled again.
+func (u *authUsecase) DisableUser(c echo.Context, userId string) error {
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.ErrUserNotFound
+	}
+
+	if err := u.authRepository.SetUserDisabled(objectID, true); err != nil {
+		return err
+	}
+
+	if err := u.revokeAllSessions(userId); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventAccountDisabled)
+
+	return nil
+}
+
+func (u *authUsecase) EnableUser(c echo.Context, userId string) error {
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.ErrUserNotFound
+	}
+
+	if err := u.authRepository.SetUserDisabled(objectID, false); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventAccountEnabled)
+
+	return nil
+}
+
+// UnlockUser lifts a login lockout of the user before it runs out.
+func (u *authUsecase) UnlockUser(c echo.Context, userId string) error {
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.ErrUserNotFound
+	}
+
+	user, err := u.authRepository.FindUserByUID(objectID)
+	if err == mongo.ErrNoDocuments {
+		return model.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := u.authRepository.ResetLoginFailures(accountSubject(user.Email)); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventAccountUnlocked)
+
+	return nil
+}
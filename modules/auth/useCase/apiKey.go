@@ -0,0 +1,122 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/secureToken"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Last use of a key is written at most this often, unless the key moves to another address
+const apiKeyTouchInterval = time.Minute
+
+func (u *authUsecase) CreateApiKey(cfg *config.Config, userId string, createReq *model.CreateApiKeyReq) (*model.CreateApiKeyRes, error) {
+	secret, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	key := jwtAuth.ApiKeyPrefix + secret
+
+	apiKey := &model.ApiKey{
+		UserId:    userId,
+		Name:      createReq.Name,
+		KeyHash:   secureToken.HmacToken(key, cfg.Jwt.ApiSecret),
+		Prefix:    key[:len(jwtAuth.ApiKeyPrefix)+6],
+		Scopes:    createReq.Scopes,
+		CreatedAt: time.Now(),
+	}
+
+	if createReq.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, createReq.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := u.authRepository.AddApiKey(apiKey); err != nil {
+		return nil, err
+	}
+
+	return &model.CreateApiKeyRes{
+		ApiKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (u *authUsecase) ListApiKeys(userId string) ([]*model.ApiKey, error) {
+	return u.authRepository.FindApiKeysByUserId(userId)
+}
+
+func (u *authUsecase) RevokeApiKey(userId string, apiKeyId string) error {
+	objectID, err := primitive.ObjectIDFromHex(apiKeyId)
+	if err != nil {
+		return model.ErrApiKeyNotFound
+	}
+
+	return u.authRepository.RevokeApiKey(userId, objectID)
+}
+
+// AuthenticateApiKey turns an API key into the same claims an access token of its owner
+// would carry, narrowed down to the scopes of the key.
+func (u *authUsecase) AuthenticateApiKey(cfg *config.Config, key string, ipAddress string) (*jwtAuth.AuthMapClaims, error) {
+	apiKey, claims, err := u.apiKeyClaims(cfg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authRepository.TouchApiKey(apiKey.ID, ipAddress, apiKeyTouchInterval); err != nil {
+		log.Printf("Error: Touch api key failed: %s", err.Error())
+	}
+
+	return claims, nil
+}
+
+func (u *authUsecase) apiKeyClaims(cfg *config.Config, key string) (*model.ApiKey, *jwtAuth.AuthMapClaims, error) {
+	if !strings.HasPrefix(key, jwtAuth.ApiKeyPrefix) {
+		return nil, nil, model.ErrInvalidApiKey
+	}
+
+	apiKey, err := u.authRepository.FindApiKeyByHash(secureToken.HmacToken(key, cfg.Jwt.ApiSecret))
+	if err != nil {
+		if errors.Is(err, model.ErrApiKeyNotFound) {
+			return nil, nil, model.ErrInvalidApiKey
+		}
+		return nil, nil, err
+	}
+
+	if apiKey.Revoked || (apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt)) {
+		return nil, nil, model.ErrInvalidApiKey
+	}
+
+	user, err := u.findUserById(apiKey.UserId)
+	if err != nil || user.Disabled {
+		return nil, nil, model.ErrInvalidApiKey
+	}
+
+	claims := &jwtAuth.AuthMapClaims{
+		Claims: &jwtAuth.Claims{
+			UserId:        apiKey.UserId,
+			RoleCode:      user.Role,
+			EmailVerified: user.EmailVerified,
+			Scope:         strings.Join(apiKey.Scopes, " "),
+			TokenVersion:  user.TokenVersion,
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       apiKey.ID.Hex(),
+			Subject:  "api-key",
+			IssuedAt: jwt.NewNumericDate(apiKey.CreatedAt),
+		},
+	}
+
+	if apiKey.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*apiKey.ExpiresAt)
+	}
+
+	return apiKey, claims, nil
+}
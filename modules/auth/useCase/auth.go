@@ -2,44 +2,118 @@ package useCase
 
 import (
 	"errors"
-	"fmt"
 	"go-auth/config"
 	"go-auth/modules/auth/model"
 	"go-auth/modules/auth/repository"
 	"go-auth/pkg/cookieHelper"
 	"go-auth/pkg/jwtAuth"
-	"net/http"
-
+	"go-auth/pkg/mailer"
+	"go-auth/pkg/oauth"
+	"go-auth/pkg/passwordHasher"
+	"go-auth/pkg/passwordPolicy"
+	"go-auth/pkg/tokenRevocation"
+	"go-auth/pkg/tokenVault"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type (
 	AuthUsecase interface {
-		RegisterByEmail(c echo.Context, cfg *config.Config, registerReq *model.RegisterReq) (*model.AccessToken, error)
+		RegisterByEmail(c echo.Context, cfg *config.Config, registerReq *model.RegisterReq) (*model.RegisterRes, error)
 		Login(c echo.Context, cfg *config.Config, loginReq *model.LoginReq) (*model.AccessToken, error)
 		Logout(c echo.Context, cfg *config.Config, logoutReq *model.LogoutReq) error
 		ReloadToken(c echo.Context, cfg *config.Config, reloadReq *model.Token) (*model.Token, error)
-		FindOrRegisterFacebookUser(userInfo *model.FacebookUser) (*model.User, error)
-		GenerateTokens(user *model.User, cfg *config.Config) *model.Token
+		BeginSocialLogin(c echo.Context, cfg *config.Config, providerName string) (string, error)
+		FinishSocialLogin(c echo.Context, cfg *config.Config, providerName string, callbackReq *model.SocialCallbackReq) (*model.SocialLoginRes, error)
+		SocialIdTokenLogin(c echo.Context, cfg *config.Config, providerName string, idTokenReq *model.SocialIdTokenReq) (*model.AccessToken, error)
+		BeginIdentityLink(c echo.Context, cfg *config.Config, userId string, providerName string) (*model.SocialLinkRes, error)
+		ListIdentities(userId string) ([]model.Identity, error)
+		UnlinkIdentity(c echo.Context, userId string, providerName string) error
+		ProviderToken(userId string, providerName string) (*model.ProviderTokenRes, error)
+		GenerateTokens(c echo.Context, user *model.User, cfg *config.Config) (*model.Token, error)
 		FindUserByUID(objectID primitive.ObjectID) (*model.User, error)
+		ListSessions(userId string, currentSessionId string) ([]*model.Session, error)
+		RevokeSession(userId string, sessionId string) error
+		RevokeOtherSessions(userId string, currentSessionId string) error
+		ForgotPassword(cfg *config.Config, forgotReq *model.ForgotPasswordReq) error
+		ResetPassword(c echo.Context, cfg *config.Config, resetReq *model.ResetPasswordReq) error
+		ChangePassword(c echo.Context, userId string, sessionId string, changeReq *model.ChangePasswordReq) error
+		VerifyEmail(verifyReq *model.VerifyEmailReq) error
+		ResendVerificationEmail(cfg *config.Config, resendReq *model.ResendVerificationReq) error
+		LoginMfa(c echo.Context, cfg *config.Config, mfaReq *model.MfaLoginReq) (*model.AccessToken, error)
+		EnrollTotp(cfg *config.Config, userId string) (*model.TotpEnrollRes, error)
+		ConfirmTotp(c echo.Context, userId string, codeReq *model.MfaCodeReq) (*model.RecoveryCodesRes, error)
+		DisableMfa(c echo.Context, cfg *config.Config, userId string, codeReq *model.MfaCodeReq) error
+		RegenerateRecoveryCodes(c echo.Context, cfg *config.Config, userId string, codeReq *model.MfaCodeReq) (*model.RecoveryCodesRes, error)
+		BeginPasskeyRegistration(userId string) (*protocol.CredentialCreation, error)
+		FinishPasskeyRegistration(userId string, name string, body io.Reader) (*model.Passkey, error)
+		BeginPasskeyLogin(loginReq *model.WebauthnLoginReq) (*protocol.CredentialAssertion, error)
+		FinishPasskeyLogin(c echo.Context, cfg *config.Config, body io.Reader) (*model.AccessToken, error)
+		OpenIdConfiguration(cfg *config.Config) *model.OpenIdConfiguration
+		UserInfo(userId string) (*model.UserInfo, error)
+		CreateOAuthClient(createReq *model.CreateOAuthClientReq) (*model.CreateOAuthClientRes, error)
+		ListOAuthClients() ([]*model.OAuthClient, error)
+		DisableOAuthClient(clientId string) error
+		Authorize(cfg *config.Config, userId string, sessionId string, authorizeReq *model.AuthorizeReq) (*model.AuthorizeRes, error)
+		Token(c echo.Context, cfg *config.Config, tokenReq *model.TokenReq) (*model.TokenRes, error)
+		CreateServiceAccount(createReq *model.CreateServiceAccountReq) (*model.ServiceAccountSecretRes, error)
+		ListServiceAccounts() ([]*model.ServiceAccount, error)
+		RotateServiceAccountSecret(cfg *config.Config, clientId string) (*model.ServiceAccountSecretRes, error)
+		DisableServiceAccount(clientId string) error
+		CreateApiKey(cfg *config.Config, userId string, createReq *model.CreateApiKeyReq) (*model.CreateApiKeyRes, error)
+		ListApiKeys(userId string) ([]*model.ApiKey, error)
+		RevokeApiKey(userId string, apiKeyId string) error
+		AuthenticateApiKey(cfg *config.Config, key string, ipAddress string) (*jwtAuth.AuthMapClaims, error)
+		Introspect(cfg *config.Config, introspectReq *model.IntrospectReq) (*model.IntrospectRes, error)
+		RevokeToken(cfg *config.Config, revokeReq *model.RevokeReq) error
+		UpdateUserRole(userId string, updateReq *model.UpdateRoleReq) error
+		UnlockUser(c echo.Context, userId string) error
+		DisableUser(c echo.Context, userId string) error
+		EnableUser(c echo.Context, userId string) error
 	}
 
 	authUsecase struct {
-		authRepository repository.AuthRepository
+		authRepository    repository.AuthRepository
+		sessionRepository repository.SessionRepository
+		oauthRepository   repository.OAuthRepository
+		revocation        *tokenRevocation.Store
+		mailer            mailer.Mailer
+		webAuthn          *webauthn.WebAuthn
+		passwordHasher    passwordHasher.PasswordHasher
+		passwordPolicy    *passwordPolicy.Policy
+		socialProviders   *oauth.Registry
+		tokenVault        *tokenVault.KeyRing
+
+		dummyHashOnce sync.Once
+		dummyHash     string
 	}
 )
 
-func NewAuthUsecase(authRepository repository.AuthRepository) AuthUsecase {
+func NewAuthUsecase(authRepository repository.AuthRepository, sessionRepository repository.SessionRepository, oauthRepository repository.OAuthRepository, revocation *tokenRevocation.Store, mailer mailer.Mailer, webAuthn *webauthn.WebAuthn, passwordHasher passwordHasher.PasswordHasher, passwordPolicy *passwordPolicy.Policy, socialProviders *oauth.Registry, tokenVault *tokenVault.KeyRing) AuthUsecase {
 	return &authUsecase{
-		authRepository: authRepository,
+		authRepository:    authRepository,
+		sessionRepository: sessionRepository,
+		oauthRepository:   oauthRepository,
+		revocation:        revocation,
+		mailer:            mailer,
+		webAuthn:          webAuthn,
+		passwordHasher:    passwordHasher,
+		passwordPolicy:    passwordPolicy,
+		socialProviders:   socialProviders,
+		tokenVault:        tokenVault,
 	}
 }
 
-func (u *authUsecase) RegisterByEmail(c echo.Context, cfg *config.Config, registerReq *model.RegisterReq) (*model.AccessToken, error) {
+func (u *authUsecase) RegisterByEmail(c echo.Context, cfg *config.Config, registerReq *model.RegisterReq) (*model.RegisterRes, error) {
 
 	user, err := u.authRepository.FindOneUserByEmail(registerReq.Email)
 	if err == nil && user != nil {
@@ -52,14 +126,18 @@ func (u *authUsecase) RegisterByEmail(c echo.Context, cfg *config.Config, regist
 		}
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerReq.Password), bcrypt.DefaultCost)
+	if err := u.checkPassword("RegisterReq.Password", registerReq.Password, registerReq.Email, nil); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := u.passwordHasher.Hash(registerReq.Password)
 	if err != nil {
 		return nil, model.ErrFailedToHashPassword
 	}
 
 	userPassport := &model.UserPassport{
 		Email:         registerReq.Email,
-		Password:      string(hashedPassword),
+		Password:      hashedPassword,
 		OauthProvider: "email",
 		Role:          "user",
 	}
@@ -69,23 +147,28 @@ func (u *authUsecase) RegisterByEmail(c echo.Context, cfg *config.Config, regist
 		return nil, errors.New("failed to add user")
 	}
 
-	userId := newUser.ID.Hex()
-
-	claims := &jwtAuth.Claims{
-		UserId:   userId,
-		RoleCode: "user",
+	// A failed email is not fatal, the user can ask for another one
+	if err := u.sendVerificationEmail(cfg, newUser); err != nil {
+		log.Printf("Error: Send verification email failed: %s", err.Error())
 	}
 
-	accessToken := u.authRepository.AccessToken(cfg, claims)
+	if cfg.EmailVerification.RequiredFor(newUser.Role) {
+		return &model.RegisterRes{
+			Message: "Please verify your email address before logging in",
+		}, nil
+	}
 
-	refreshToken := u.authRepository.RefreshToken(cfg, claims)
+	tokens, err := u.GenerateTokens(c, newUser, cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	cookie := cookieHelper.NewCookieHelper(c, cfg)
 
-	cookie.SetRefreshToken(refreshToken)
+	cookie.SetRefreshToken(tokens.RefreshToken)
 
-	return &model.AccessToken{
-		AccessToken: accessToken,
+	return &model.RegisterRes{
+		AccessToken: tokens.AccessToken,
 	}, nil
 }
 
@@ -95,146 +178,341 @@ func (u *authUsecase) FindUserByUID(objectID primitive.ObjectID) (*model.User, e
 	return user, error
 }
 
-func (u *authUsecase) FindOrRegisterFacebookUser(userInfo *model.FacebookUser) (*model.User, error) {
-	facebookID := userInfo.OauthId
-	user, err := u.authRepository.FindByProviderId(facebookID)
-
-	if err != nil {
+func (u *authUsecase) Login(c echo.Context, cfg *config.Config, loginReq *model.LoginReq) (*model.AccessToken, error) {
+	if err := u.checkLoginBlock(c, loginReq.Email); err != nil {
 		return nil, err
 	}
 
-	if user == nil && err != nil {
-		userPassport := &model.UserPassport{
-			Email:         userInfo.Email,
-			OauthProvider: "facebook",
-			OauthId:       userInfo.OauthId,
-		}
+	user, err := u.authRepository.FindOneUserByEmail(loginReq.Email)
 
-		if user, err := u.authRepository.AddUser(userPassport); err != nil {
-			return user, err
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			// An unknown email takes as long and answers the same as a wrong password
+			u.verifyDummyPassword(loginReq.Password)
+			u.loginFailed(c, cfg, loginReq.Email, nil)
+			return nil, model.ErrInvalidCredentials
 		}
+		return nil, err
 	}
 
-	return user, err
-}
-
-func (u *authUsecase) Login(c echo.Context, cfg *config.Config, loginReq *model.LoginReq) (*model.AccessToken, error) {
-	user, err := u.authRepository.FindOneUserByEmail(loginReq.Email)
-
+	valid, err := u.passwordHasher.Verify(loginReq.Password, user.Password)
 	if err != nil {
-		return nil, err
+		log.Printf("Error: Verify password failed: %s", err.Error())
+	}
+	if !valid {
+		u.loginFailed(c, cfg, loginReq.Email, user)
+		return nil, model.ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginReq.Password)); err != nil {
-		fmt.Println(err.Error())
-		return nil, errors.New("error, password is invalid")
+	// The password is only ever known right now, so this is when an outdated hash is replaced
+	if u.passwordHasher.NeedsRehash(user.Password) {
+		u.rehashPassword(user, loginReq.Password)
 	}
 
-	userId := user.ID.Hex()
+	if user.Disabled {
+		return nil, model.ErrAccountDisabled
+	}
 
-	claims := &jwtAuth.Claims{
-		UserId:   userId,
-		RoleCode: user.Role,
+	if !user.EmailVerified && cfg.EmailVerification.RequiredFor(user.Role) {
+		return nil, model.ErrEmailNotVerified
 	}
 
-	accessToken := u.authRepository.AccessToken(cfg, claims)
+	// The failures only start over once the second factor is passed as well
+	if user.MfaEnabled {
+		return u.mfaChallenge(cfg, user)
+	}
+
+	tokens, err := u.GenerateTokens(c, user, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	refreshToken := u.authRepository.RefreshToken(cfg, claims)
+	u.loginSucceeded(loginReq.Email)
 
 	cookie := cookieHelper.NewCookieHelper(c, cfg)
 
-	cookie.SetRefreshToken(refreshToken)
+	cookie.SetRefreshToken(tokens.RefreshToken)
 
 	return &model.AccessToken{
-		AccessToken: accessToken,
+		AccessToken: tokens.AccessToken,
+		IdToken:     tokens.IdToken,
 	}, nil
 
 }
 
-func (u *authUsecase) Logout(c echo.Context, cfg *config.Config, logoutReq *model.LogoutReq) error {
-	// Parse the refresh token to extract the claims
-	claims := &jwtAuth.AuthMapClaims{}
-	token, err := jwt.ParseWithClaims(logoutReq.RefreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Jwt.RefreshTokenSecret), nil
+// verifyDummyPassword checks the password against a hash of the current algorithm, for
+// logins of unknown accounts. The hash is made on first use.
+func (u *authUsecase) verifyDummyPassword(password string) {
+	u.dummyHashOnce.Do(func() {
+		hash, err := u.passwordHasher.Hash("dummy password")
+		if err != nil {
+			log.Printf("Error: Hash dummy password failed: %s", err.Error())
+			return
+		}
+		u.dummyHash = hash
 	})
-	if err != nil {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+
+	if u.dummyHash != "" {
+		u.passwordHasher.Verify(password, u.dummyHash)
 	}
+}
 
-	if !token.Valid {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired token"})
+// rehashPassword replaces the hash of the user with one of the current algorithm and
+// parameters. The login goes on either way, the next one can try again.
+func (u *authUsecase) rehashPassword(user *model.User, password string) {
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Error: Rehash password failed: %s", err.Error())
+		return
 	}
 
-	expirationTime := claims.ExpiresAt.Time
+	if err := u.authRepository.RehashUserPassword(user.ID, user.Password, hashedPassword); err != nil {
+		log.Printf("Error: Rehash password failed: %s", err.Error())
+	}
+}
 
-	err = u.authRepository.AddBlacklistToken(logoutReq.RefreshToken, expirationTime)
+// Logout ends the session of the refresh token and revokes the access token when the
+// client still sent it along.
+func (u *authUsecase) Logout(c echo.Context, cfg *config.Config, logoutReq *model.LogoutReq) error {
+	revoked, err := u.revokeRefreshToken(cfg, logoutReq.RefreshToken, "")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to blacklist token"})
+		return err
+	}
+
+	if !revoked {
+		return model.ErrInvalidRefreshToken
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{"message": "Logged out successfully"})
+	if logoutReq.AccessToken != "" {
+		if _, err := u.revokeAccessToken(cfg, logoutReq.AccessToken, ""); err != nil {
+			return err
+		}
+	}
+
+	cookie := cookieHelper.NewCookieHelper(c, cfg)
+
+	cookie.ClearRefreshToken()
+
+	return nil
 }
 
 func (u *authUsecase) ReloadToken(c echo.Context, cfg *config.Config, reloadReq *model.Token) (*model.Token, error) {
 
-	accessClaims := &jwtAuth.AuthMapClaims{}
-	accessToken, err := jwt.ParseWithClaims(reloadReq.AccessToken, accessClaims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(cfg.Jwt.AccessTokenSecret), nil
-	})
+	if reloadReq.AccessToken != "" {
+		accessClaims := &jwtAuth.AuthMapClaims{}
+		accessToken, err := jwt.ParseWithClaims(reloadReq.AccessToken, accessClaims, jwtAuth.Keyfunc(cfg.Jwt.AccessTokenSecret))
+
+		if err == nil && accessToken.Valid && !jwtAuth.IsRevoked(accessClaims) {
+			return reloadReq, nil
+		}
 
-	if err == nil && accessToken.Valid {
-		return reloadReq, nil
+		// If the access token error is something else than expiry, return an error
+		if !errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, model.ErrInvalidAccessToken
+		}
 	}
 
-	if err != nil && errors.Is(err, jwt.ErrTokenExpired) {
-		refreshClaims := &jwtAuth.AuthMapClaims{}
-		refreshToken, refreshTokenErr := jwt.ParseWithClaims(reloadReq.RefreshToken, refreshClaims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.Jwt.RefreshTokenSecret), nil
-		})
+	return u.refreshTokens(c, cfg, reloadReq.RefreshToken, "")
+}
 
-		if refreshTokenErr != nil || !refreshToken.Valid {
-			return nil, model.ErrInvalidRefreshToken
+// refreshTokens exchanges a refresh token for the next token pair of its family. The
+// token must have been issued to clientId, which is empty for our own frontends.
+func (u *authUsecase) refreshTokens(c echo.Context, cfg *config.Config, refreshTokenString string, clientId string) (*model.Token, error) {
+	refreshClaims := &jwtAuth.AuthMapClaims{}
+	refreshToken, refreshTokenErr := jwt.ParseWithClaims(refreshTokenString, refreshClaims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Jwt.RefreshTokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+
+	if refreshTokenErr != nil && errors.Is(refreshTokenErr, jwt.ErrTokenExpired) {
+		return nil, model.ErrExpiredRefreshToken
+	}
+
+	if refreshTokenErr != nil || !refreshToken.Valid || refreshClaims.Claims == nil || refreshClaims.ID == "" {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	if refreshClaims.ClientId != clientId {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	record, err := u.authRepository.FindRefreshToken(refreshClaims.ID)
+	if err != nil {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	if record.Revoked {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	rotated, err := u.authRepository.RotateRefreshToken(record.Jti)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token was already exchanged once, so whoever presents it now may hold a stolen copy.
+	// Kill the whole family so that neither party can keep refreshing.
+	if !rotated {
+		if err := u.revokeFamily(record.FamilyId); err != nil {
+			return nil, err
 		}
 
-		if refreshTokenErr != nil && errors.Is(err, jwt.ErrTokenExpired) {
-			return nil, model.ErrExpiredRefreshToken
+		event := &model.SecurityEvent{
+			UserId:    record.UserId,
+			Type:      model.SecurityEventRefreshTokenReuse,
+			FamilyId:  record.FamilyId,
+			Jti:       record.Jti,
+			IpAddress: c.RealIP(),
+			UserAgent: c.Request().UserAgent(),
+		}
+		if err := u.authRepository.AddSecurityEvent(event); err != nil {
+			log.Printf("Error: Record security event failed: %s", err.Error())
 		}
 
-		expirationTime := refreshClaims.ExpiresAt.Time
+		return nil, model.ErrRefreshTokenReused
+	}
 
-		if err := u.authRepository.AddBlacklistToken(reloadReq.RefreshToken, expirationTime); err != nil {
-			return nil, model.ErrAddBlacklistTokenFailed
-		}
+	expirationTime := refreshClaims.ExpiresAt.Time
 
-		// Generate new access token and refresh token
-		newAccessToken := u.authRepository.AccessToken(cfg, refreshClaims.Claims)
-		newRefreshToken := u.authRepository.RefreshToken(cfg, refreshClaims.Claims)
+	if err := u.revocation.Revoke(refreshClaims.ID, expirationTime); err != nil {
+		return nil, model.ErrAddBlacklistTokenFailed
+	}
 
-		return &model.Token{
-			AccessToken:  newAccessToken,
-			RefreshToken: newRefreshToken,
-		}, nil
+	// Reload the user so that a verified email or a new role shows up in the new tokens
+	uid, err := primitive.ObjectIDFromHex(record.UserId)
+	if err != nil {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	user, err := u.authRepository.FindUserByUID(uid)
+	if err != nil || user.Disabled {
+		return nil, model.ErrInvalidRefreshToken
+	}
+
+	claims := &jwtAuth.Claims{
+		UserId:        record.UserId,
+		RoleCode:      user.Role,
+		EmailVerified: user.EmailVerified,
+		FamilyId:      record.FamilyId,
+		ClientId:      refreshClaims.ClientId,
+		Scope:         refreshClaims.Scope,
+		TokenVersion:  user.TokenVersion,
+	}
+
+	// Generate new access token and refresh token in the same family
+	newAccessToken, err := u.authRepository.AccessToken(cfg, claims)
+	if err != nil {
+		return nil, err
+	}
+	newRefreshToken, err := u.authRepository.RefreshToken(cfg, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionExpiresAt := jwtAuth.JwtTimeDurationHour(cfg.Jwt.RefreshTokenDuration).Time
+	session, err := u.sessionRepository.TouchSession(record.FamilyId, c.RealIP(), sessionExpiresAt)
+	if err != nil {
+		if errors.Is(err, model.ErrSessionNotFound) {
+			return nil, model.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	grant := &tokenGrant{
+		ClientId: claims.ClientId,
+		Scope:    claims.Scope,
+		AuthTime: session.CreatedAt,
 	}
 
-	// If the access token error is something else, return an error
-	return nil, model.ErrInvalidAccessToken
+	return &model.Token{
+		AccessToken:  newAccessToken,
+		RefreshToken: newRefreshToken,
+		IdToken:      u.grantIdToken(cfg, user, grant),
+	}, nil
 }
 
-func (u *authUsecase) GenerateTokens(user *model.User, cfg *config.Config) *model.Token {
+// GenerateTokens starts a new session for the user and issues the first token pair of it.
+func (u *authUsecase) GenerateTokens(c echo.Context, user *model.User, cfg *config.Config) (*model.Token, error) {
+	return u.issueTokens(c, cfg, user, &tokenGrant{})
+}
+
+// issueTokens starts a new session for the user and issues the first token pair of it
+// on behalf of the client of the grant.
+func (u *authUsecase) issueTokens(c echo.Context, cfg *config.Config, user *model.User, grant *tokenGrant) (*model.Token, error) {
+	if user.Disabled {
+		return nil, model.ErrAccountDisabled
+	}
 
 	userId := user.ID.Hex()
 
 	claims := &jwtAuth.Claims{
-		UserId:   userId,
-		RoleCode: user.Role,
+		UserId:        userId,
+		RoleCode:      user.Role,
+		EmailVerified: user.EmailVerified,
+		FamilyId:      jwtAuth.NewTokenId(),
+		ClientId:      grant.ClientId,
+		Scope:         grant.Scope,
+		TokenVersion:  user.TokenVersion,
 	}
 
-	accessToken := u.authRepository.AccessToken(cfg, claims)
+	session := &model.Session{
+		ID:         claims.FamilyId,
+		UserId:     userId,
+		UserAgent:  c.Request().UserAgent(),
+		IpAddress:  c.RealIP(),
+		CreatedAt:  time.Now(),
+		LastUsedAt: time.Now(),
+		ExpiresAt:  jwtAuth.JwtTimeDurationHour(cfg.Jwt.RefreshTokenDuration).Time,
+	}
 
-	refreshToken := u.authRepository.RefreshToken(cfg, claims)
+	if err := u.sessionRepository.AddSession(session); err != nil {
+		return nil, err
+	}
+
+	if grant.AuthTime.IsZero() {
+		grant.AuthTime = session.CreatedAt
+	}
+
+	accessToken, err := u.authRepository.AccessToken(cfg, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := u.authRepository.RefreshToken(cfg, claims)
+	if err != nil {
+		return nil, err
+	}
 
 	return &model.Token{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		IdToken:      u.grantIdToken(cfg, user, grant),
+	}, nil
+}
+
+// revokeFamily ends the session of a refresh token family together with every token in it.
+func (u *authUsecase) revokeFamily(familyId string) error {
+	if err := u.authRepository.RevokeRefreshTokenFamily(familyId); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepository.RevokeSession(familyId); err != nil {
+		return err
+	}
+
+	// Access tokens carry the family too, so they stop working along with the session
+	return u.revocation.RevokeFamily(familyId)
+}
+
+// revokeAllSessions signs the user out of every device, access tokens included.
+func (u *authUsecase) revokeAllSessions(userId string) error {
+	if err := u.authRepository.RevokeRefreshTokensByUserId(userId); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepository.RevokeSessionsByUserId(userId); err != nil {
+		return err
 	}
+
+	_, err := u.revocation.BumpTokenVersion(userId)
+	return err
 }
@@ -0,0 +1,215 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/modules/auth/repository"
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const testRefreshSecret = "refresh-secret"
+
+// fakeRefreshRepository keeps refresh token records in memory
+type fakeRefreshRepository struct {
+	repository.AuthRepository
+	user    *model.User
+	records map[string]*model.RefreshTokenRecord
+	events  []*model.SecurityEvent
+}
+
+func (r *fakeRefreshRepository) FindUserByUID(objectID primitive.ObjectID) (*model.User, error) {
+	if objectID != r.user.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	return r.user, nil
+}
+
+func (r *fakeRefreshRepository) AccessToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error) {
+	return "access-token", nil
+}
+
+func (r *fakeRefreshRepository) RefreshToken(cfg *config.Config, claims *jwtAuth.Claims) (string, error) {
+	factory := jwtAuth.NewRefreshToken(cfg.Jwt.RefreshTokenSecret, cfg.Jwt.RefreshTokenDuration, claims)
+	signed, err := factory.SignToken()
+	if err != nil {
+		return "", err
+	}
+
+	tokenClaims := factory.GetClaims()
+	r.records[tokenClaims.ID] = &model.RefreshTokenRecord{
+		Jti:       tokenClaims.ID,
+		FamilyId:  claims.FamilyId,
+		UserId:    claims.UserId,
+		ExpiresAt: tokenClaims.ExpiresAt.Time,
+	}
+
+	return signed, nil
+}
+
+func (r *fakeRefreshRepository) FindRefreshToken(jti string) (*model.RefreshTokenRecord, error) {
+	record, ok := r.records[jti]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return record, nil
+}
+
+func (r *fakeRefreshRepository) RotateRefreshToken(jti string) (bool, error) {
+	record, ok := r.records[jti]
+	if !ok || record.Revoked || record.RotatedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	record.RotatedAt = &now
+	return true, nil
+}
+
+func (r *fakeRefreshRepository) RevokeRefreshTokenFamily(familyId string) error {
+	for _, record := range r.records {
+		if record.FamilyId == familyId {
+			record.Revoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshRepository) AddSecurityEvent(event *model.SecurityEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeSessionRepository) TouchSession(sessionId string, ipAddress string, expiresAt time.Time) (*model.Session, error) {
+	session, ok := r.sessions[sessionId]
+	if !ok || session.Revoked {
+		return nil, model.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func TestRefreshTokens(t *testing.T) {
+	const familyId = "family"
+	user := &model.User{ID: primitive.NewObjectID(), Role: "user"}
+
+	cfg := &config.Config{
+		Jwt:  &config.Jwt{RefreshTokenSecret: testRefreshSecret, RefreshTokenDuration: 24},
+		Oidc: &config.Oidc{},
+	}
+
+	tests := []struct {
+		name string
+		// prepare runs between issuing the refresh token and presenting it, and may
+		// return another token to present instead
+		prepare       func(t *testing.T, u *authUsecase, token string) string
+		clientId      string
+		wantErr       error
+		wantRevoked   bool
+		wantReuseSeen bool
+	}{
+		{
+			name: "first use",
+		},
+		{
+			name: "replay of a rotated token",
+			prepare: func(t *testing.T, u *authUsecase, token string) string {
+				if _, err := u.refreshTokens(newRefreshContext(), cfg, token, ""); err != nil {
+					t.Fatalf("refreshTokens() error = %v", err)
+				}
+				return token
+			},
+			wantErr:       model.ErrRefreshTokenReused,
+			wantRevoked:   true,
+			wantReuseSeen: true,
+		},
+		{
+			name: "revoked family",
+			prepare: func(t *testing.T, u *authUsecase, token string) string {
+				if err := u.revokeFamily(familyId); err != nil {
+					t.Fatalf("revokeFamily() error = %v", err)
+				}
+				return token
+			},
+			wantErr:     model.ErrInvalidRefreshToken,
+			wantRevoked: true,
+		},
+		{
+			name:     "token of another client",
+			clientId: "client-id",
+			wantErr:  model.ErrInvalidRefreshToken,
+		},
+		{
+			name: "token signed with another algorithm",
+			prepare: func(t *testing.T, u *authUsecase, token string) string {
+				parsed, _ := jwt.ParseWithClaims(token, &jwtAuth.AuthMapClaims{}, func(token *jwt.Token) (interface{}, error) {
+					return []byte(testRefreshSecret), nil
+				})
+				resigned, err := jwt.NewWithClaims(jwt.SigningMethodHS512, parsed.Claims).SignedString([]byte(testRefreshSecret))
+				if err != nil {
+					t.Fatalf("SignedString() error = %v", err)
+				}
+				return resigned
+			},
+			wantErr: model.ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRefreshRepository{user: user, records: make(map[string]*model.RefreshTokenRecord)}
+			sessions := newFakeSessionRepository(&model.Session{ID: familyId, UserId: user.ID.Hex()})
+			u := &authUsecase{
+				authRepository:    repo,
+				sessionRepository: sessions,
+				revocation:        newRevocationStore(),
+			}
+
+			token, err := repo.RefreshToken(cfg, &jwtAuth.Claims{UserId: user.ID.Hex(), FamilyId: familyId})
+			if err != nil {
+				t.Fatalf("RefreshToken() error = %v", err)
+			}
+			if tt.prepare != nil {
+				token = tt.prepare(t, u, token)
+			}
+
+			tokens, err := u.refreshTokens(newRefreshContext(), cfg, token, tt.clientId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("refreshTokens() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.RefreshToken == token) {
+				t.Errorf("refreshTokens() = %+v, want a new token pair", tokens)
+			}
+
+			for _, record := range repo.records {
+				if record.Revoked != tt.wantRevoked {
+					t.Errorf("refresh token %s revoked = %v, want %v", record.Jti, record.Revoked, tt.wantRevoked)
+				}
+			}
+			if got := sessions.sessions[familyId].Revoked; got != tt.wantRevoked {
+				t.Errorf("session revoked = %v, want %v", got, tt.wantRevoked)
+			}
+			if got := u.revocation.IsRevoked(accessTokenClaims(user.ID.Hex(), familyId)); got != tt.wantRevoked {
+				t.Errorf("access tokens of the family revoked = %v, want %v", got, tt.wantRevoked)
+			}
+
+			reuseSeen := len(repo.events) == 1 && repo.events[0].Type == model.SecurityEventRefreshTokenReuse && repo.events[0].FamilyId == familyId
+			if reuseSeen != tt.wantReuseSeen || (!tt.wantReuseSeen && len(repo.events) > 0) {
+				t.Errorf("security events = %v, want reuse recorded %v", repo.events, tt.wantReuseSeen)
+			}
+		})
+	}
+}
+
+func newRefreshContext() echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
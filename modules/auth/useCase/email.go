@@ -0,0 +1,78 @@
+package useCase
+
+import (
+	"fmt"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/secureToken"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (u *authUsecase) sendVerificationEmail(cfg *config.Config, user *model.User) error {
+	token, err := secureToken.NewToken(32)
+	if err != nil {
+		return err
+	}
+
+	verificationToken := &model.EmailVerificationToken{
+		TokenHash: secureToken.HashToken(token),
+		UserId:    user.ID.Hex(),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Duration(cfg.EmailVerification.Duration) * time.Hour),
+		CreatedAt: time.Now(),
+	}
+
+	if err := u.authRepository.AddEmailVerificationToken(verificationToken); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", cfg.EmailVerification.Url, url.QueryEscape(token))
+	body := fmt.Sprintf("Welcome! Please confirm your email address by opening the link below "+
+		"within %d hours:\n%s", cfg.EmailVerification.Duration, link)
+
+	return u.mailer.Send(user.Email, "Verify your email address", body)
+}
+
+func (u *authUsecase) VerifyEmail(verifyReq *model.VerifyEmailReq) error {
+	verificationToken, err := u.authRepository.ConsumeEmailVerificationToken(secureToken.HashToken(verifyReq.Token))
+	if err != nil {
+		return err
+	}
+
+	uid, err := primitive.ObjectIDFromHex(verificationToken.UserId)
+	if err != nil {
+		return model.ErrInvalidVerificationToken
+	}
+
+	return u.authRepository.MarkEmailVerified(uid, verificationToken.Email)
+}
+
+// ResendVerificationEmail sends a new verification link, at most once per configured interval
+// per address. Unknown or already verified addresses are silently ignored.
+func (u *authUsecase) ResendVerificationEmail(cfg *config.Config, resendReq *model.ResendVerificationReq) error {
+	allowed, err := u.authRepository.AllowEmailVerificationResend(resendReq.Email, time.Duration(cfg.EmailVerification.ResendInterval)*time.Second)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return model.ErrTooManyRequests
+	}
+
+	user, err := u.authRepository.FindOneUserByEmail(resendReq.Email)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if user.EmailVerified || user.OauthProvider != "email" {
+		return nil
+	}
+
+	return u.sendVerificationEmail(cfg, user)
+}
@@ -0,0 +1,108 @@
+package useCase
+
+import (
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/oauth"
+	"log"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// BeginIdentityLink returns the url of the provider for a signed in user to link. The
+// callback is the one of a login, which links instead of logging in.
+func (u *authUsecase) BeginIdentityLink(c echo.Context, cfg *config.Config, userId string, providerName string) (*model.SocialLinkRes, error) {
+	if _, err := u.findUserById(userId); err != nil {
+		return nil, err
+	}
+
+	redirectUrl, err := u.beginSocialLogin(c, cfg, providerName, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SocialLinkRes{Url: redirectUrl}, nil
+}
+
+func (u *authUsecase) ListIdentities(userId string) ([]model.Identity, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Identities == nil {
+		return []model.Identity{}, nil
+	}
+
+	return user.Identities, nil
+}
+
+// UnlinkIdentity removes the identity of the provider from the user, as long as the user
+// can still log in with a password, a passkey or another identity.
+func (u *authUsecase) UnlinkIdentity(c echo.Context, userId string, providerName string) error {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return err
+	}
+
+	removed, err := u.authRepository.RemoveUserIdentity(user.ID, providerName)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		for _, identity := range user.Identities {
+			if identity.Provider == providerName {
+				return model.ErrLastLoginMethod
+			}
+		}
+		return model.ErrIdentityNotFound
+	}
+
+	// Nobody may call the provider for the user once it is unlinked
+	if err := u.authRepository.DeleteProviderToken(userId, providerName); err != nil {
+		log.Printf("Error: Delete %s token failed: %s", providerName, err.Error())
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventIdentityUnlinked)
+
+	return nil
+}
+
+// linkIdentity adds the identity to the user. Linking it again to the same user is fine,
+// linking it to a second user is not.
+func (u *authUsecase) linkIdentity(c echo.Context, userId string, identity *oauth.Identity) (*model.Identity, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := u.authRepository.FindByProviderId(identity.Provider, identity.Subject)
+	if err == nil {
+		if owner.ID != user.ID {
+			return nil, model.ErrIdentityAlreadyLinked
+		}
+
+		for _, linked := range owner.Identities {
+			if linked.Provider == identity.Provider && linked.Subject == identity.Subject {
+				return &linked, nil
+			}
+		}
+	}
+
+	linked := &model.Identity{
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: time.Now(),
+	}
+
+	if err := u.authRepository.AddUserIdentity(user.ID, linked); err != nil {
+		return nil, err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventIdentityLinked)
+
+	return linked, nil
+}
@@ -0,0 +1,153 @@
+package useCase
+
+import (
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// authenticateResourceServer checks the caller of the introspection endpoint. Only callers
+// that can keep a secret may ask, so public clients are turned away.
+func (u *authUsecase) authenticateResourceServer(clientId string, clientSecret string) error {
+	if strings.HasPrefix(clientId, serviceAccountIdPrefix) {
+		_, err := u.authenticateServiceAccount(clientId, clientSecret)
+		return err
+	}
+
+	client, err := u.authenticateClient(clientId, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	if client.Public {
+		return model.ErrOAuthInvalidClient()
+	}
+
+	return nil
+}
+
+// Introspect tells whether a token is active right now. Every way a token can fail ends
+// in the same inactive answer, so callers learn nothing about why.
+func (u *authUsecase) Introspect(cfg *config.Config, introspectReq *model.IntrospectReq) (*model.IntrospectRes, error) {
+	if err := u.authenticateResourceServer(introspectReq.ClientId, introspectReq.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	token := introspectReq.Token
+	inactive := &model.IntrospectRes{Active: false}
+
+	if token == "" {
+		return inactive, nil
+	}
+
+	var claims *jwtAuth.AuthMapClaims
+	var active bool
+	var err error
+
+	switch {
+	case strings.HasPrefix(token, jwtAuth.ApiKeyPrefix):
+		_, claims, err = u.apiKeyClaims(cfg, token)
+		active = err == nil
+	case introspectReq.TokenTypeHint == "refresh_token":
+		// The hint only decides what is tried first, RFC 7662 section 2.1
+		if claims, active = u.introspectRefreshToken(cfg, token); !active {
+			claims, active = u.introspectAccessToken(cfg, token)
+		}
+	default:
+		if claims, active = u.introspectAccessToken(cfg, token); !active {
+			claims, active = u.introspectRefreshToken(cfg, token)
+		}
+	}
+
+	if !active {
+		return inactive, nil
+	}
+
+	blacklisted, err := u.revocation.IsBlacklisted(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if blacklisted {
+		return inactive, nil
+	}
+
+	res := &model.IntrospectRes{
+		Active:   true,
+		Sub:      claims.UserId,
+		Scope:    claims.Scope,
+		ClientId: claims.ClientId,
+		Iss:      claims.Issuer,
+		Jti:      claims.ID,
+	}
+
+	// token_type is the type of RFC 6749 section 5.1, refresh tokens are not used as one
+	if claims.Subject != "refresh-token" {
+		res.TokenType = "Bearer"
+	}
+
+	// Service tokens have no user, the service account is their subject
+	if res.Sub == "" {
+		res.Sub = claims.ClientId
+	}
+	if claims.ExpiresAt != nil {
+		res.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.Iat = claims.IssuedAt.Unix()
+	}
+
+	return res, nil
+}
+
+func (u *authUsecase) introspectAccessToken(cfg *config.Config, token string) (*jwtAuth.AuthMapClaims, bool) {
+	claims, err := jwtAuth.ParseToken(cfg.Jwt.AccessTokenSecret, token)
+	if err != nil || claims.Claims == nil {
+		return nil, false
+	}
+
+	if claims.Subject != "access-token" && claims.Subject != "service-token" {
+		return nil, false
+	}
+
+	if jwtAuth.IsRevoked(claims) {
+		return nil, false
+	}
+
+	// A signed out session takes its access tokens with it, even before they expire
+	if claims.FamilyId != "" {
+		session, err := u.sessionRepository.FindSession(claims.FamilyId)
+		if err != nil || session.Revoked {
+			return nil, false
+		}
+	}
+
+	return claims, true
+}
+
+func (u *authUsecase) introspectRefreshToken(cfg *config.Config, token string) (*jwtAuth.AuthMapClaims, bool) {
+	claims := &jwtAuth.AuthMapClaims{}
+	refreshToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Jwt.RefreshTokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !refreshToken.Valid || claims.Claims == nil || claims.ID == "" {
+		return nil, false
+	}
+
+	if claims.Subject != "refresh-token" {
+		return nil, false
+	}
+
+	record, err := u.authRepository.FindRefreshToken(claims.ID)
+	if err != nil {
+		return nil, false
+	}
+
+	if record.Revoked || record.RotatedAt != nil {
+		return nil, false
+	}
+
+	return claims, true
+}
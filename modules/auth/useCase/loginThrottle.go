@@ -0,0 +1,109 @@
+package useCase
+
+import (
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func accountSubject(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipSubject(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// loginDelay doubles with every failure, up to the configured maximum.
+func loginDelay(cfg *config.Config, failures int64) time.Duration {
+	delay := time.Duration(cfg.LoginThrottle.DelayBase) * time.Millisecond
+	maxDelay := time.Duration(cfg.LoginThrottle.MaxDelay) * time.Millisecond
+
+	for i := int64(1); i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return delay
+}
+
+// checkLoginBlock fails when the account or the address may not try to log in yet. Only
+// a lock of the account itself is reported as such, everything else is too many requests.
+// It fails open when Redis is down, an outage must not keep everybody out.
+func (u *authUsecase) checkLoginBlock(c echo.Context, email string) error {
+	for _, subject := range []string{accountSubject(email), ipSubject(c.RealIP())} {
+		block, err := u.authRepository.FindLoginBlock(subject)
+		if err != nil {
+			log.Printf("Error: Check login block failed: %s", err.Error())
+			continue
+		}
+
+		if block == nil {
+			continue
+		}
+
+		err = model.ErrTooManyRequests
+		if block.Locked && subject == accountSubject(email) {
+			err = model.ErrAccountLocked
+		}
+
+		return &model.LoginBlockedError{Err: err, RetryAfter: block.RetryAfter}
+	}
+
+	return nil
+}
+
+// recordLoginFailure delays the next attempt of the subject, or locks it out once it
+// failed maxFailures times. Reports whether the subject got locked.
+func (u *authUsecase) recordLoginFailure(cfg *config.Config, subject string, maxFailures int64) bool {
+	window := time.Duration(cfg.LoginThrottle.FailureWindow) * time.Minute
+
+	failures, err := u.authRepository.AddLoginFailure(subject, window)
+	if err != nil {
+		log.Printf("Error: Record login failure failed: %s", err.Error())
+		return false
+	}
+
+	block := &model.LoginBlock{RetryAfter: loginDelay(cfg, failures)}
+	if maxFailures > 0 && failures >= maxFailures {
+		block = &model.LoginBlock{
+			Locked:     true,
+			RetryAfter: time.Duration(cfg.LoginThrottle.LockoutDuration) * time.Minute,
+		}
+	}
+
+	if block.RetryAfter <= 0 {
+		return false
+	}
+
+	if err := u.authRepository.BlockLogin(subject, block); err != nil {
+		log.Printf("Error: Block login failed: %s", err.Error())
+		return false
+	}
+
+	return block.Locked
+}
+
+// loginFailed counts a wrong password against both the account and the address. Unknown
+// accounts are counted too, so a lockout does not tell which emails are registered.
+func (u *authUsecase) loginFailed(c echo.Context, cfg *config.Config, email string, user *model.User) {
+	if u.recordLoginFailure(cfg, accountSubject(email), cfg.LoginThrottle.MaxAccountFailures) && user != nil {
+		u.recordSecurityEvent(c, user.ID.Hex(), model.SecurityEventAccountLocked)
+	}
+
+	u.recordLoginFailure(cfg, ipSubject(c.RealIP()), cfg.LoginThrottle.MaxIpFailures)
+}
+
+// loginSucceeded starts the count of the account over. The address keeps its count, or
+// a single account of its own would let an attacker reset it at will.
+func (u *authUsecase) loginSucceeded(email string) {
+	if err := u.authRepository.ResetLoginFailures(accountSubject(email)); err != nil {
+		log.Printf("Error: Reset login failures failed: %s", err.Error())
+	}
+}
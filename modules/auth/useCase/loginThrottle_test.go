@@ -0,0 +1,264 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/modules/auth/repository"
+	"go-auth/pkg/passwordHasher"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testIpAddress = "192.0.2.1"
+
+// fakeThrottleRepository keeps login failures, blocks and security events in memory
+type fakeThrottleRepository struct {
+	repository.AuthRepository
+	users    map[string]*model.User
+	failures map[string]int64
+	blocks   map[string]*model.LoginBlock
+	events   []string
+}
+
+func newFakeThrottleRepository(users ...*model.User) *fakeThrottleRepository {
+	r := &fakeThrottleRepository{
+		users:    make(map[string]*model.User),
+		failures: make(map[string]int64),
+		blocks:   make(map[string]*model.LoginBlock),
+	}
+	for _, user := range users {
+		r.users[user.Email] = user
+	}
+	return r
+}
+
+func (r *fakeThrottleRepository) FindOneUserByEmail(email string) (*model.User, error) {
+	user, ok := r.users[email]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
+}
+
+func (r *fakeThrottleRepository) AddLoginFailure(subject string, window time.Duration) (int64, error) {
+	r.failures[subject]++
+	return r.failures[subject], nil
+}
+
+func (r *fakeThrottleRepository) BlockLogin(subject string, block *model.LoginBlock) error {
+	r.blocks[subject] = block
+	return nil
+}
+
+func (r *fakeThrottleRepository) FindLoginBlock(subject string) (*model.LoginBlock, error) {
+	return r.blocks[subject], nil
+}
+
+func (r *fakeThrottleRepository) ResetLoginFailures(subject string) error {
+	delete(r.failures, subject)
+	delete(r.blocks, subject)
+	return nil
+}
+
+func (r *fakeThrottleRepository) AddSecurityEvent(event *model.SecurityEvent) error {
+	r.events = append(r.events, event.Type)
+	return nil
+}
+
+// countingHasher counts the passwords it verified
+type countingHasher struct {
+	passwordHasher.PasswordHasher
+	verified int
+}
+
+func (h *countingHasher) Verify(password string, hash string) (bool, error) {
+	h.verified++
+	return h.PasswordHasher.Verify(password, hash)
+}
+
+func newThrottleConfig() *config.Config {
+	return &config.Config{
+		LoginThrottle: &config.LoginThrottle{
+			MaxAccountFailures: 3,
+			MaxIpFailures:      5,
+			FailureWindow:      15,
+			LockoutDuration:    30,
+			DelayBase:          100,
+			MaxDelay:           1000,
+		},
+	}
+}
+
+func newThrottleContext() echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	req.RemoteAddr = testIpAddress + ":1234"
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		delayBase int64
+		maxDelay  int64
+		failures  int64
+		want      time.Duration
+	}{
+		{name: "first failure", delayBase: 100, maxDelay: 1000, failures: 1, want: 100 * time.Millisecond},
+		{name: "doubles", delayBase: 100, maxDelay: 1000, failures: 3, want: 400 * time.Millisecond},
+		{name: "capped", delayBase: 100, maxDelay: 1000, failures: 5, want: time.Second},
+		{name: "stays capped", delayBase: 100, maxDelay: 1000, failures: 100, want: time.Second},
+		{name: "base above the cap", delayBase: 2000, maxDelay: 1000, failures: 1, want: time.Second},
+		{name: "no delay", delayBase: 0, maxDelay: 0, failures: 4, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				LoginThrottle: &config.LoginThrottle{DelayBase: tt.delayBase, MaxDelay: tt.maxDelay},
+			}
+
+			if got := loginDelay(cfg, tt.failures); got != tt.want {
+				t.Errorf("loginDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	const email = "user@example.com"
+	user := &model.User{ID: primitive.NewObjectID(), Email: email}
+	lockout := 30 * time.Minute
+
+	tests := []struct {
+		name        string
+		user        *model.User
+		failures    int
+		ipFailures  int64
+		wantAccount *model.LoginBlock
+		wantIp      *model.LoginBlock
+		wantEvents  int
+	}{
+		{
+			name:        "first failure delays",
+			user:        user,
+			failures:    1,
+			wantAccount: &model.LoginBlock{RetryAfter: 100 * time.Millisecond},
+			wantIp:      &model.LoginBlock{RetryAfter: 100 * time.Millisecond},
+		},
+		{
+			name:        "account locks at its maximum",
+			user:        user,
+			failures:    3,
+			wantAccount: &model.LoginBlock{Locked: true, RetryAfter: lockout},
+			wantIp:      &model.LoginBlock{RetryAfter: 400 * time.Millisecond},
+			wantEvents:  1,
+		},
+		{
+			name:        "unknown account locks without an event",
+			failures:    3,
+			wantAccount: &model.LoginBlock{Locked: true, RetryAfter: lockout},
+			wantIp:      &model.LoginBlock{RetryAfter: 400 * time.Millisecond},
+		},
+		{
+			name:        "address locks at its maximum",
+			user:        user,
+			failures:    1,
+			ipFailures:  4,
+			wantAccount: &model.LoginBlock{RetryAfter: 100 * time.Millisecond},
+			wantIp:      &model.LoginBlock{Locked: true, RetryAfter: lockout},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeThrottleRepository()
+			repo.failures[ipSubject(testIpAddress)] = tt.ipFailures
+			u := &authUsecase{authRepository: repo}
+			cfg := newThrottleConfig()
+			c := newThrottleContext()
+
+			for i := 0; i < tt.failures; i++ {
+				u.loginFailed(c, cfg, email, tt.user)
+			}
+
+			if got := repo.blocks[accountSubject(email)]; *got != *tt.wantAccount {
+				t.Errorf("account block = %+v, want %+v", *got, *tt.wantAccount)
+			}
+			if got := repo.blocks[ipSubject(testIpAddress)]; *got != *tt.wantIp {
+				t.Errorf("address block = %+v, want %+v", *got, *tt.wantIp)
+			}
+			if len(repo.events) != tt.wantEvents {
+				t.Errorf("security events = %v, want %d", repo.events, tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestLoginSucceeded(t *testing.T) {
+	const email = "User@Example.com"
+	repo := newFakeThrottleRepository()
+	u := &authUsecase{authRepository: repo}
+	cfg := newThrottleConfig()
+	c := newThrottleContext()
+
+	u.loginFailed(c, cfg, email, nil)
+	u.loginFailed(c, cfg, email, nil)
+	u.loginSucceeded("user@example.com")
+
+	if _, ok := repo.failures[accountSubject(email)]; ok {
+		t.Errorf("account failures were kept")
+	}
+	if _, ok := repo.blocks[accountSubject(email)]; ok {
+		t.Errorf("account block was kept")
+	}
+	if got := repo.failures[ipSubject(testIpAddress)]; got != 2 {
+		t.Errorf("address failures = %d, want 2", got)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	hasher := passwordHasher.NewBcryptHasher(bcrypt.MinCost)
+	hash, err := hasher.Hash("correct password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	user := &model.User{ID: primitive.NewObjectID(), Email: "user@example.com", Password: hash}
+
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "wrong password", email: "user@example.com"},
+		{name: "unknown email", email: "nobody@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeThrottleRepository(user)
+			counting := &countingHasher{PasswordHasher: hasher}
+			u := &authUsecase{authRepository: repo, passwordHasher: counting}
+
+			_, err := u.Login(newThrottleContext(), newThrottleConfig(), &model.LoginReq{
+				Email:    tt.email,
+				Password: "wrong password",
+			})
+			if !errors.Is(err, model.ErrInvalidCredentials) {
+				t.Errorf("Login() error = %v, want %v", err, model.ErrInvalidCredentials)
+			}
+			if counting.verified != 1 {
+				t.Errorf("Login() verified %d passwords, want 1", counting.verified)
+			}
+			if got := repo.failures[accountSubject(tt.email)]; got != 1 {
+				t.Errorf("account failures = %d, want 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package useCase
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/cookieHelper"
+	"go-auth/pkg/secureToken"
+	"go-auth/pkg/totp"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns the codes to show to the user once and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, secureToken.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
+
+func (u *authUsecase) findUserById(userId string) (*model.User, error) {
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.authRepository.FindUserByUID(uid)
+}
+
+func (u *authUsecase) recordSecurityEvent(c echo.Context, userId string, eventType string) {
+	event := &model.SecurityEvent{
+		UserId:    userId,
+		Type:      eventType,
+		IpAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+	if err := u.authRepository.AddSecurityEvent(event); err != nil {
+		log.Printf("Error: Record security event failed: %s", err.Error())
+	}
+}
+
+// verifyTotp accepts a code of the current TOTP secret of the user, at most once.
+func (u *authUsecase) verifyTotp(user *model.User, code string) error {
+	step, ok := totp.Validate(user.TotpSecret, code, time.Now())
+	if !ok {
+		return model.ErrInvalidMfaCode
+	}
+
+	fresh, err := u.authRepository.MarkTotpStepUsed(user.ID.Hex(), step, (2*totp.Skew+1)*totp.Period*time.Second)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return model.ErrInvalidMfaCode
+	}
+
+	return nil
+}
+
+// verifySecondFactor accepts either a TOTP code or one of the recovery codes of the user.
+func (u *authUsecase) verifySecondFactor(c echo.Context, user *model.User, code string) error {
+	if !user.MfaEnabled {
+		return model.ErrMfaNotEnabled
+	}
+
+	err := u.verifyTotp(user, code)
+	if err == nil || err != model.ErrInvalidMfaCode {
+		return err
+	}
+
+	used, err := u.authRepository.ConsumeRecoveryCode(user.ID, secureToken.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return model.ErrInvalidMfaCode
+	}
+
+	u.recordSecurityEvent(c, user.ID.Hex(), model.SecurityEventRecoveryCodeUsed)
+
+	return nil
+}
+
+func mfaSubject(userId string) string {
+	return "mfa:" + userId
+}
+
+// verifyMfaChange runs verify for a signed-in user who changes their MFA. Wrong codes are
+// throttled and locked out like wrong passwords, so a stolen access token is not enough
+// to guess one.
+func (u *authUsecase) verifyMfaChange(cfg *config.Config, user *model.User, verify func() error) error {
+	subject := mfaSubject(user.ID.Hex())
+
+	block, err := u.authRepository.FindLoginBlock(subject)
+	if err != nil {
+		log.Printf("Error: Check MFA block failed: %s", err.Error())
+	} else if block != nil {
+		return &model.LoginBlockedError{Err: model.ErrTooManyRequests, RetryAfter: block.RetryAfter}
+	}
+
+	if err := verify(); err != nil {
+		if err == model.ErrInvalidMfaCode {
+			u.recordLoginFailure(cfg, subject, cfg.Mfa.MaxAttempts)
+		}
+		return err
+	}
+
+	if err := u.authRepository.ResetLoginFailures(subject); err != nil {
+		log.Printf("Error: Reset MFA failures failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// mfaChallenge starts the second login step and returns the token that identifies it.
+func (u *authUsecase) mfaChallenge(cfg *config.Config, user *model.User) (*model.AccessToken, error) {
+	token, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(cfg.Mfa.ChallengeDuration) * time.Minute
+	if err := u.authRepository.AddMfaChallenge(secureToken.HashToken(token), user.ID.Hex(), ttl); err != nil {
+		return nil, err
+	}
+
+	return &model.AccessToken{
+		MfaRequired: true,
+		MfaToken:    token,
+	}, nil
+}
+
+func (u *authUsecase) LoginMfa(c echo.Context, cfg *config.Config, mfaReq *model.MfaLoginReq) (*model.AccessToken, error) {
+	tokenHash := secureToken.HashToken(mfaReq.MfaToken)
+
+	userId, err := u.authRepository.FindMfaChallenge(tokenHash, cfg.Mfa.MaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, model.ErrInvalidMfaToken
+	}
+
+	if err := u.checkLoginBlock(c, user.Email); err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count against the account like wrong passwords, so the lockout also
+	// covers guessing the second factor with a fresh challenge each time
+	if err := u.verifySecondFactor(c, user, mfaReq.Code); err != nil {
+		if err == model.ErrInvalidMfaCode {
+			u.loginFailed(c, cfg, user.Email, user)
+		}
+		return nil, err
+	}
+
+	if err := u.authRepository.DeleteMfaChallenge(tokenHash); err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.GenerateTokens(c, user, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	u.loginSucceeded(user.Email)
+
+	cookie := cookieHelper.NewCookieHelper(c, cfg)
+
+	cookie.SetRefreshToken(tokens.RefreshToken)
+
+	return &model.AccessToken{
+		AccessToken: tokens.AccessToken,
+		IdToken:     tokens.IdToken,
+	}, nil
+}
+
+func (u *authUsecase) EnrollTotp(cfg *config.Config, userId string) (*model.TotpEnrollRes, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MfaEnabled {
+		return nil, model.ErrMfaAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authRepository.SetPendingTotpSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TotpEnrollRes{
+		Secret:     secret,
+		OtpauthUri: totp.URI(cfg.Mfa.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp turns MFA on once the user proves the authenticator app holds the pending secret.
+func (u *authUsecase) ConfirmTotp(c echo.Context, userId string, codeReq *model.MfaCodeReq) (*model.RecoveryCodesRes, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MfaEnabled {
+		return nil, model.ErrMfaAlreadyEnabled
+	}
+
+	if user.PendingTotp == "" {
+		return nil, model.ErrMfaNotEnrolled
+	}
+
+	user.TotpSecret = user.PendingTotp
+	if err := u.verifyTotp(user, codeReq.Code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authRepository.EnableMfa(user.ID, user.TotpSecret, hashes); err != nil {
+		return nil, err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventMfaEnabled)
+
+	return &model.RecoveryCodesRes{
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (u *authUsecase) DisableMfa(c echo.Context, cfg *config.Config, userId string, codeReq *model.MfaCodeReq) error {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return err
+	}
+
+	err = u.verifyMfaChange(cfg, user, func() error {
+		return u.verifySecondFactor(c, user, codeReq.Code)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := u.authRepository.DisableMfa(user.ID); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventMfaDisabled)
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code. It needs a TOTP code, so a leaked
+// recovery code cannot be used to mint new ones.
+func (u *authUsecase) RegenerateRecoveryCodes(c echo.Context, cfg *config.Config, userId string, codeReq *model.MfaCodeReq) (*model.RecoveryCodesRes, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.MfaEnabled {
+		return nil, model.ErrMfaNotEnabled
+	}
+
+	err = u.verifyMfaChange(cfg, user, func() error {
+		return u.verifyTotp(user, codeReq.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.authRepository.SetRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventRecoveryCodesRegenerated)
+
+	return &model.RecoveryCodesRes{
+		RecoveryCodes: codes,
+	}, nil
+}
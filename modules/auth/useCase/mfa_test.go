@@ -0,0 +1,173 @@
+package useCase
+
+import (
+	"go-auth/modules/auth/model"
+	"go-auth/modules/auth/repository"
+	"go-auth/pkg/secureToken"
+	"go-auth/pkg/totp"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeMfaRepository keeps the used TOTP steps and the recovery codes in memory
+type fakeMfaRepository struct {
+	repository.AuthRepository
+	usedSteps     map[string]bool
+	recoveryCodes map[string]bool
+	events        []*model.SecurityEvent
+}
+
+func newFakeMfaRepository(recoveryCodes ...string) *fakeMfaRepository {
+	r := &fakeMfaRepository{
+		usedSteps:     make(map[string]bool),
+		recoveryCodes: make(map[string]bool),
+	}
+	for _, code := range recoveryCodes {
+		r.recoveryCodes[secureToken.HashToken(normalizeRecoveryCode(code))] = true
+	}
+	return r
+}
+
+func (r *fakeMfaRepository) MarkTotpStepUsed(userId string, step int64, ttl time.Duration) (bool, error) {
+	key := userId + ":" + strconv.FormatInt(step, 10)
+	if r.usedSteps[key] {
+		return false, nil
+	}
+	r.usedSteps[key] = true
+	return true, nil
+}
+
+func (r *fakeMfaRepository) ConsumeRecoveryCode(objectID primitive.ObjectID, recoveryCodeHash string) (bool, error) {
+	if !r.recoveryCodes[recoveryCodeHash] {
+		return false, nil
+	}
+	delete(r.recoveryCodes, recoveryCodeHash)
+	return true, nil
+}
+
+func (r *fakeMfaRepository) AddSecurityEvent(event *model.SecurityEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func newMfaUser(t *testing.T) *model.User {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	return &model.User{ID: primitive.NewObjectID(), MfaEnabled: true, TotpSecret: secret}
+}
+
+func currentTotpCode(t *testing.T, user *model.User, offset int64) string {
+	code, err := totp.Code(user.TotpSecret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("Code() error = %v", err)
+	}
+	return code
+}
+
+func TestVerifyTotp(t *testing.T) {
+	tests := []struct {
+		name    string
+		codes   func(t *testing.T, user *model.User) []string
+		wantErr []error
+	}{
+		{
+			name: "current code",
+			codes: func(t *testing.T, user *model.User) []string {
+				return []string{currentTotpCode(t, user, 0)}
+			},
+			wantErr: []error{nil},
+		},
+		{
+			name: "replay of the same step",
+			codes: func(t *testing.T, user *model.User) []string {
+				code := currentTotpCode(t, user, 0)
+				return []string{code, code}
+			},
+			wantErr: []error{nil, model.ErrInvalidMfaCode},
+		},
+		{
+			name: "codes of neighbouring steps",
+			codes: func(t *testing.T, user *model.User) []string {
+				return []string{currentTotpCode(t, user, -1), currentTotpCode(t, user, 1)}
+			},
+			wantErr: []error{nil, nil},
+		},
+		{
+			name: "code outside the skew",
+			codes: func(t *testing.T, user *model.User) []string {
+				return []string{currentTotpCode(t, user, totp.Skew+2)}
+			},
+			wantErr: []error{model.ErrInvalidMfaCode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := newMfaUser(t)
+			u := &authUsecase{authRepository: newFakeMfaRepository()}
+
+			for i, code := range tt.codes(t, user) {
+				if err := u.verifyTotp(user, code); err != tt.wantErr[i] {
+					t.Errorf("verifyTotp() #%d error = %v, want %v", i, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerifySecondFactorRecoveryCode(t *testing.T) {
+	const recoveryCode = "abcd-efgh"
+
+	tests := []struct {
+		name       string
+		code       string
+		mfaEnabled bool
+		wantErr    error
+		wantUsed   bool
+	}{
+		{name: "recovery code", code: recoveryCode, mfaEnabled: true, wantUsed: true},
+		{name: "recovery code without dash in upper case", code: "ABCDEFGH", mfaEnabled: true, wantUsed: true},
+		{name: "unknown recovery code", code: "zzzz-zzzz", mfaEnabled: true, wantErr: model.ErrInvalidMfaCode},
+		{name: "mfa disabled", code: recoveryCode, wantErr: model.ErrMfaNotEnabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeMfaRepository(recoveryCode)
+			u := &authUsecase{authRepository: repo}
+			user := newMfaUser(t)
+			user.MfaEnabled = tt.mfaEnabled
+
+			if err := u.verifySecondFactor(newMfaContext(), user, tt.code); err != tt.wantErr {
+				t.Fatalf("verifySecondFactor() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if used := len(repo.recoveryCodes) == 0; used != tt.wantUsed {
+				t.Errorf("recovery code used = %v, want %v", used, tt.wantUsed)
+			}
+			if recorded := len(repo.events) == 1 && repo.events[0].Type == model.SecurityEventRecoveryCodeUsed; recorded != tt.wantUsed {
+				t.Errorf("security events = %v, want recovery code use recorded %v", repo.events, tt.wantUsed)
+			}
+
+			// A recovery code works once
+			if tt.wantUsed {
+				if err := u.verifySecondFactor(newMfaContext(), user, tt.code); err != model.ErrInvalidMfaCode {
+					t.Errorf("second verifySecondFactor() error = %v, want %v", err, model.ErrInvalidMfaCode)
+				}
+			}
+		})
+	}
+}
+
+func newMfaContext() echo.Context {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", nil)
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
@@ -0,0 +1,355 @@
+package useCase
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/secureToken"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// tokenGrant describes on whose behalf tokens are issued. An empty ClientId means our
+// own frontends, which get the full access of the user.
+type tokenGrant struct {
+	ClientId string
+	Scope    string
+	Nonce    string
+	AuthTime time.Time
+}
+
+// A S256 code challenge is the base64url encoded sha256 of the verifier
+var codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// RFC 7636 section 4.1
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+func hasScope(scope string, want string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(set []string, values []string) bool {
+	for _, v := range values {
+		found := false
+		for _, s := range set {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func withQuery(rawUrl string, params url.Values) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		for _, v := range values {
+			query.Add(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// authorizeError sends the error back to the client, as the redirect uri is known to be
+// registered by now.
+func authorizeError(redirectUri string, state string, err *model.OAuthError) *model.AuthorizeRes {
+	params := url.Values{"error": {err.Code}}
+	if err.Description != "" {
+		params.Set("error_description", err.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	return &model.AuthorizeRes{RedirectTo: withQuery(redirectUri, params)}
+}
+
+func (u *authUsecase) CreateOAuthClient(createReq *model.CreateOAuthClientReq) (*model.CreateOAuthClientRes, error) {
+	client := &model.OAuthClient{
+		ClientId:      jwtAuth.NewTokenId(),
+		Name:          createReq.Name,
+		RedirectUris:  createReq.RedirectUris,
+		AllowedScopes: createReq.AllowedScopes,
+		Public:        createReq.Public,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	// Public clients cannot keep a secret, they prove themselves with PKCE instead
+	var secret string
+	if !client.Public {
+		var err error
+		secret, err = secureToken.NewToken(32)
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash = secureToken.HashToken(secret)
+	}
+
+	if err := u.oauthRepository.AddClient(client); err != nil {
+		return nil, err
+	}
+
+	return &model.CreateOAuthClientRes{
+		OAuthClient:  client,
+		ClientSecret: secret,
+	}, nil
+}
+
+func (u *authUsecase) ListOAuthClients() ([]*model.OAuthClient, error) {
+	return u.oauthRepository.FindClients()
+}
+
+func (u *authUsecase) DisableOAuthClient(clientId string) error {
+	return u.oauthRepository.DisableClient(clientId)
+}
+
+// Authorize checks an authorization request of the signed in user. It answers with the
+// redirect back to the client, or with the consent the user still has to give.
+func (u *authUsecase) Authorize(cfg *config.Config, userId string, sessionId string, authorizeReq *model.AuthorizeReq) (*model.AuthorizeRes, error) {
+	client, err := u.oauthRepository.FindClient(authorizeReq.ClientId)
+	if err != nil {
+		if errors.Is(err, model.ErrOAuthClientNotFound) {
+			return nil, model.ErrOAuthInvalidRequest("Unknown client")
+		}
+		return nil, err
+	}
+
+	if client.Disabled {
+		return nil, model.ErrOAuthInvalidRequest("Unknown client")
+	}
+
+	// Until the redirect uri is matched against the registered ones, errors must not be
+	// sent to it, or we would be an open redirector.
+	redirectUri := authorizeReq.RedirectUri
+	if redirectUri == "" && len(client.RedirectUris) == 1 {
+		redirectUri = client.RedirectUris[0]
+	}
+	if !containsAll(client.RedirectUris, []string{redirectUri}) {
+		return nil, model.ErrOAuthInvalidRequest("Invalid redirect_uri")
+	}
+
+	state := authorizeReq.State
+
+	if authorizeReq.ResponseType != "code" {
+		return authorizeError(redirectUri, state, model.ErrOAuthUnsupportedResponseType()), nil
+	}
+
+	scopes := strings.Fields(authorizeReq.Scope)
+	if len(scopes) == 0 {
+		scopes = client.AllowedScopes
+	}
+	if !containsAll(client.AllowedScopes, scopes) {
+		return authorizeError(redirectUri, state, model.ErrOAuthInvalidScope("The client may not ask for this scope")), nil
+	}
+
+	if authorizeReq.CodeChallenge == "" {
+		if client.Public {
+			return authorizeError(redirectUri, state, model.ErrOAuthInvalidRequest("Public clients must use PKCE")), nil
+		}
+	} else {
+		if authorizeReq.CodeChallengeMethod != model.CodeChallengeMethodS256 {
+			return authorizeError(redirectUri, state, model.ErrOAuthInvalidRequest("Only the S256 code_challenge_method is supported")), nil
+		}
+		if !codeChallengePattern.MatchString(authorizeReq.CodeChallenge) {
+			return authorizeError(redirectUri, state, model.ErrOAuthInvalidRequest("Invalid code_challenge")), nil
+		}
+	}
+
+	consent, err := u.oauthRepository.FindConsent(userId, client.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	if consent == nil || !containsAll(consent.Scopes, scopes) {
+		if authorizeReq.Approve == nil {
+			return &model.AuthorizeRes{
+				ConsentRequired: true,
+				ClientName:      client.Name,
+				Scopes:          scopes,
+			}, nil
+		}
+
+		if !*authorizeReq.Approve {
+			return authorizeError(redirectUri, state, model.ErrOAuthAccessDenied()), nil
+		}
+
+		if err := u.oauthRepository.SaveConsent(userId, client.ClientId, scopes); err != nil {
+			return nil, err
+		}
+	}
+
+	// The ID token reports when the user actually signed in, not when the code was swapped
+	authTime := time.Now()
+	if session, err := u.sessionRepository.FindSession(sessionId); err == nil {
+		authTime = session.CreatedAt
+	}
+
+	code, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationCode := &model.AuthorizationCode{
+		ClientId:        client.ClientId,
+		UserId:          userId,
+		RedirectUri:     redirectUri,
+		RedirectUriSent: authorizeReq.RedirectUri != "",
+		Scope:           strings.Join(scopes, " "),
+		Nonce:           authorizeReq.Nonce,
+		CodeChallenge:   authorizeReq.CodeChallenge,
+		AuthTime:        authTime,
+	}
+
+	ttl := time.Duration(cfg.Oidc.AuthorizationCodeDuration) * time.Second
+	if err := u.oauthRepository.AddAuthorizationCode(secureToken.HashToken(code), authorizationCode, ttl); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"code": {code}}
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	return &model.AuthorizeRes{RedirectTo: withQuery(redirectUri, params)}, nil
+}
+
+// authenticateClient checks the credentials sent to the token endpoint.
+func (u *authUsecase) authenticateClient(clientId string, clientSecret string) (*model.OAuthClient, error) {
+	if clientId == "" {
+		return nil, model.ErrOAuthInvalidClient()
+	}
+
+	client, err := u.oauthRepository.FindClient(clientId)
+	if err != nil {
+		if errors.Is(err, model.ErrOAuthClientNotFound) {
+			return nil, model.ErrOAuthInvalidClient()
+		}
+		return nil, err
+	}
+
+	if client.Disabled {
+		return nil, model.ErrOAuthInvalidClient()
+	}
+
+	if !client.Public && !secureToken.CompareHash(clientSecret, client.SecretHash) {
+		return nil, model.ErrOAuthInvalidClient()
+	}
+
+	return client, nil
+}
+
+func (u *authUsecase) Token(c echo.Context, cfg *config.Config, tokenReq *model.TokenReq) (*model.TokenRes, error) {
+	// Service accounts are not OAuth clients, they only ever use this one grant
+	if tokenReq.GrantType == model.GrantTypeClientCredentials {
+		return u.clientCredentials(cfg, tokenReq)
+	}
+
+	client, err := u.authenticateClient(tokenReq.ClientId, tokenReq.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens *model.Token
+	var scope string
+
+	switch tokenReq.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		tokens, scope, err = u.exchangeAuthorizationCode(c, cfg, client, tokenReq)
+	case model.GrantTypeRefreshToken:
+		// The scope of a refreshed token never changes, so it is left out of the response
+		tokens, err = u.refreshTokens(c, cfg, tokenReq.RefreshToken, client.ClientId)
+		if errors.Is(err, model.ErrRefreshTokenReused) || errors.Is(err, model.ErrInvalidRefreshToken) || errors.Is(err, model.ErrExpiredRefreshToken) {
+			err = model.ErrOAuthInvalidGrant(err.Error())
+		}
+	default:
+		return nil, model.ErrOAuthUnsupportedGrantType()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenRes{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    cfg.Jwt.AccessTokenDuration * 60,
+		RefreshToken: tokens.RefreshToken,
+		IdToken:      tokens.IdToken,
+		Scope:        scope,
+	}, nil
+}
+
+func (u *authUsecase) exchangeAuthorizationCode(c echo.Context, cfg *config.Config, client *model.OAuthClient, tokenReq *model.TokenReq) (*model.Token, string, error) {
+	if tokenReq.Code == "" {
+		return nil, "", model.ErrOAuthInvalidRequest("Missing code")
+	}
+
+	code, err := u.oauthRepository.TakeAuthorizationCode(secureToken.HashToken(tokenReq.Code))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if code.ClientId != client.ClientId {
+		return nil, "", model.ErrOAuthInvalidGrant("Invalid authorization code")
+	}
+
+	// A redirect_uri the authorize request left out may be left out here too, but one that
+	// is sent has to match
+	if (code.RedirectUriSent || tokenReq.RedirectUri != "") && tokenReq.RedirectUri != code.RedirectUri {
+		return nil, "", model.ErrOAuthInvalidGrant("Invalid redirect_uri")
+	}
+
+	if code.CodeChallenge != "" {
+		if !codeVerifierPattern.MatchString(tokenReq.CodeVerifier) {
+			return nil, "", model.ErrOAuthInvalidGrant("Invalid code_verifier")
+		}
+
+		sum := sha256.Sum256([]byte(tokenReq.CodeVerifier))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+			return nil, "", model.ErrOAuthInvalidGrant("Invalid code_verifier")
+		}
+	}
+
+	user, err := u.findUserById(code.UserId)
+	if err != nil || user.Disabled {
+		return nil, "", model.ErrOAuthInvalidGrant("Invalid authorization code")
+	}
+
+	tokens, err := u.issueTokens(c, cfg, user, &tokenGrant{
+		ClientId: client.ClientId,
+		Scope:    code.Scope,
+		Nonce:    code.Nonce,
+		AuthTime: code.AuthTime,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return tokens, code.Scope, nil
+}
@@ -0,0 +1,87 @@
+package useCase
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/modules/auth/repository"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeOAuthRepository hands out a single authorization code
+type fakeOAuthRepository struct {
+	repository.OAuthRepository
+	code *model.AuthorizationCode
+}
+
+func (r *fakeOAuthRepository) TakeAuthorizationCode(codeHash string) (*model.AuthorizationCode, error) {
+	code := *r.code
+	return &code, nil
+}
+
+// fakeAuthRepository finds no user, which ends the exchange right after the
+// code_verifier was accepted
+type fakeAuthRepository struct {
+	repository.AuthRepository
+}
+
+func (r *fakeAuthRepository) FindUserByUID(objectID primitive.ObjectID) (*model.User, error) {
+	return nil, mongo.ErrNoDocuments
+}
+
+func TestExchangeAuthorizationCodePkce(t *testing.T) {
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	const (
+		invalidVerifier = "invalid_grant: Invalid code_verifier"
+		// The fake user is missing, so this is what a good verifier ends in
+		passedVerifier = "invalid_grant: Invalid authorization code"
+	)
+
+	client := &model.OAuthClient{ClientId: "client-id"}
+
+	tests := []struct {
+		name      string
+		challenge string
+		verifier  string
+		want      string
+	}{
+		{name: "matching verifier", challenge: challenge, verifier: verifier, want: passedVerifier},
+		{name: "other verifier", challenge: challenge, verifier: strings.Repeat("w", 43), want: invalidVerifier},
+		{name: "missing verifier", challenge: challenge, verifier: "", want: invalidVerifier},
+		{name: "verifier too short", challenge: challenge, verifier: verifier[:42], want: invalidVerifier},
+		{name: "verifier too long", challenge: challenge, verifier: strings.Repeat("v", 129), want: invalidVerifier},
+		{name: "verifier with other characters", challenge: challenge, verifier: strings.Repeat("v", 42) + "+", want: invalidVerifier},
+		{name: "challenge sent as verifier", challenge: challenge, verifier: challenge, want: invalidVerifier},
+		{name: "no challenge", challenge: "", verifier: "", want: passedVerifier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &authUsecase{
+				authRepository: &fakeAuthRepository{},
+				oauthRepository: &fakeOAuthRepository{code: &model.AuthorizationCode{
+					ClientId:      client.ClientId,
+					UserId:        primitive.NewObjectID().Hex(),
+					CodeChallenge: tt.challenge,
+				}},
+			}
+
+			_, _, err := u.exchangeAuthorizationCode(nil, &config.Config{}, client, &model.TokenReq{
+				GrantType:    model.GrantTypeAuthorizationCode,
+				Code:         "code",
+				CodeVerifier: tt.verifier,
+			})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("exchangeAuthorizationCode() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
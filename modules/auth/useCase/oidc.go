@@ -0,0 +1,88 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"log"
+	"time"
+)
+
+// idToken returns an ID token for the user, or nothing when the issuer only has the
+// shared HS256 secret, which relying parties cannot verify.
+func (u *authUsecase) idToken(cfg *config.Config, user *model.User, audience string, nonce string, authTime time.Time) string {
+	idToken, err := jwtAuth.NewIdToken(user.ID.Hex(), audience, cfg.Oidc.IdTokenDuration, authTime, &jwtAuth.IdTokenClaims{
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Nonce:         nonce,
+	})
+	if err != nil {
+		if !errors.Is(err, jwtAuth.ErrNoSigningKey) {
+			log.Printf("Error: Sign id token failed: %s", err.Error())
+		}
+		return ""
+	}
+
+	return idToken
+}
+
+// grantIdToken returns the ID token of a grant. Our own frontends always get one, third
+// party clients only when they asked for the openid scope.
+func (u *authUsecase) grantIdToken(cfg *config.Config, user *model.User, grant *tokenGrant) string {
+	if grant.ClientId == "" {
+		return u.idToken(cfg, user, cfg.Oidc.ClientId, grant.Nonce, grant.AuthTime)
+	}
+
+	if !hasScope(grant.Scope, model.ScopeOpenId) {
+		return ""
+	}
+
+	return u.idToken(cfg, user, grant.ClientId, grant.Nonce, grant.AuthTime)
+}
+
+func (u *authUsecase) OpenIdConfiguration(cfg *config.Config) *model.OpenIdConfiguration {
+	algs := make([]string, 0)
+	if ks := jwtAuth.GetKeyStore(); ks != nil {
+		if key, err := ks.SigningKey(); err == nil {
+			algs = append(algs, key.Alg)
+		}
+	}
+
+	// Browsers go to the login UI, /oauth/authorize of the API needs an access token
+	authorizationEndpoint := cfg.Oidc.AuthorizationEndpoint
+	if authorizationEndpoint == "" {
+		authorizationEndpoint = cfg.Oidc.Issuer + "/oauth/authorize"
+	}
+
+	return &model.OpenIdConfiguration{
+		Issuer:                            cfg.Oidc.Issuer,
+		AuthorizationEndpoint:             authorizationEndpoint,
+		TokenEndpoint:                     cfg.Oidc.Issuer + "/oauth/token",
+		IntrospectionEndpoint:             cfg.Oidc.Issuer + "/oauth/introspect",
+		RevocationEndpoint:                cfg.Oidc.Issuer + "/oauth/revoke",
+		UserinfoEndpoint:                  cfg.Oidc.Issuer + "/userinfo",
+		JwksUri:                           cfg.Oidc.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{"openid", "email"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials},
+		CodeChallengeMethodsSupported:     []string{model.CodeChallengeMethodS256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  algs,
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified"},
+	}
+}
+
+func (u *authUsecase) UserInfo(userId string) (*model.UserInfo, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserInfo{
+		Sub:           user.ID.Hex(),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package useCase
+
+import (
+	"go-auth/config"
+	"testing"
+)
+
+func TestOpenIdConfigurationAuthorizationEndpoint(t *testing.T) {
+	tests := []struct {
+		name                  string
+		authorizationEndpoint string
+		want                  string
+	}{
+		{name: "login UI", authorizationEndpoint: "https://login.example.com/authorize", want: "https://login.example.com/authorize"},
+		{name: "not set", want: "https://auth.example.com/oauth/authorize"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Oidc: &config.Oidc{
+				Issuer:                "https://auth.example.com",
+				AuthorizationEndpoint: tt.authorizationEndpoint,
+			}}
+
+			got := (&authUsecase{}).OpenIdConfiguration(cfg).AuthorizationEndpoint
+			if got != tt.want {
+				t.Errorf("AuthorizationEndpoint = %s, want %s", got, tt.want)
+			}
+			if tokenEndpoint := (&authUsecase{}).OpenIdConfiguration(cfg).TokenEndpoint; tokenEndpoint != "https://auth.example.com/oauth/token" {
+				t.Errorf("TokenEndpoint = %s, want it on the issuer", tokenEndpoint)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package useCase
+
+import (
+	"fmt"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/secureToken"
+	"go-auth/utils"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ForgotPassword emails a reset link to an email account. It reports success for unknown
+// addresses as well, so the endpoint cannot be used to find out who has an account.
+func (u *authUsecase) ForgotPassword(cfg *config.Config, forgotReq *model.ForgotPasswordReq) error {
+	user, err := u.authRepository.FindOneUserByEmail(forgotReq.Email)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if user.OauthProvider != "email" {
+		return nil
+	}
+
+	token, err := secureToken.NewToken(32)
+	if err != nil {
+		return err
+	}
+
+	resetToken := &model.PasswordResetToken{
+		TokenHash: secureToken.HashToken(token),
+		UserId:    user.ID.Hex(),
+		ExpiresAt: time.Now().Add(time.Duration(cfg.PasswordReset.Duration) * time.Minute),
+		CreatedAt: time.Now(),
+	}
+
+	if err := u.authRepository.AddPasswordResetToken(resetToken); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", cfg.PasswordReset.Url, url.QueryEscape(token))
+	body := fmt.Sprintf("Someone asked to reset the password of your account.\n\n"+
+		"Open the link below within %d minutes to choose a new password:\n%s\n\n"+
+		"If it was not you, you can ignore this email.", cfg.PasswordReset.Duration, link)
+
+	// A failure here must look like success as well, or it tells that the account exists
+	if err := u.mailer.Send(user.Email, "Reset your password", body); err != nil {
+		log.Printf("Error: Send password reset email failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere.
+// The token is only used up once the password passed the policy, so the user can try again.
+func (u *authUsecase) ResetPassword(c echo.Context, cfg *config.Config, resetReq *model.ResetPasswordReq) error {
+	tokenHash := secureToken.HashToken(resetReq.Token)
+
+	resetToken, err := u.authRepository.FindPasswordResetToken(tokenHash)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.findUserById(resetToken.UserId)
+	if err != nil {
+		return model.ErrInvalidResetToken
+	}
+
+	if err := u.checkPassword("ResetPasswordReq.Password", resetReq.Password, user.Email, user); err != nil {
+		return err
+	}
+
+	if _, err := u.authRepository.ConsumePasswordResetToken(tokenHash); err != nil {
+		return err
+	}
+
+	if err := u.updatePassword(user, resetReq.Password); err != nil {
+		return err
+	}
+
+	if err := u.revokeAllSessions(resetToken.UserId); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, resetToken.UserId, model.SecurityEventPasswordReset)
+
+	return nil
+}
+
+// ChangePassword replaces the password of a signed in user, who has to know the current
+// one. Every other session is signed out.
+func (u *authUsecase) ChangePassword(c echo.Context, userId string, sessionId string, changeReq *model.ChangePasswordReq) error {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return err
+	}
+
+	valid, err := u.passwordHasher.Verify(changeReq.CurrentPassword, user.Password)
+	if err != nil || !valid {
+		return model.ErrInvalidPassword
+	}
+
+	if err := u.checkPassword("ChangePasswordReq.Password", changeReq.Password, user.Email, user); err != nil {
+		return err
+	}
+
+	if err := u.updatePassword(user, changeReq.Password); err != nil {
+		return err
+	}
+
+	if err := u.RevokeOtherSessions(userId, sessionId); err != nil {
+		return err
+	}
+
+	u.recordSecurityEvent(c, userId, model.SecurityEventPasswordChanged)
+
+	return nil
+}
+
+// checkPassword holds a new password to the password policy and reports what it breaks as
+// validation errors of field. The user is nil on registration, when there is no history.
+func (u *authUsecase) checkPassword(field string, password string, email string, user *model.User) error {
+	fieldErrors := make(utils.FieldErrors, 0)
+
+	for _, violation := range u.passwordPolicy.Check(password, email) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: field, Tag: violation.Tag, Param: violation.Param})
+	}
+
+	if user != nil && u.isRecentPassword(user, password) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: field, Tag: "reused", Param: strconv.Itoa(u.passwordPolicy.HistorySize)})
+	}
+
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+
+	return nil
+}
+
+// isRecentPassword reports whether the password is one of the latest of the user, which
+// the policy does not allow again.
+func (u *authUsecase) isRecentPassword(user *model.User, password string) bool {
+	if u.passwordPolicy.HistorySize <= 0 {
+		return false
+	}
+
+	hashes := append([]string{user.Password}, user.PasswordHistory...)
+	if len(hashes) > u.passwordPolicy.HistorySize {
+		hashes = hashes[:u.passwordPolicy.HistorySize]
+	}
+
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+
+		if valid, err := u.passwordHasher.Verify(password, hash); err == nil && valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *authUsecase) updatePassword(user *model.User, password string) error {
+	hashedPassword, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		return model.ErrFailedToHashPassword
+	}
+
+	return u.authRepository.UpdateUserPassword(user.ID, hashedPassword, u.passwordPolicy.HistorySize)
+}
@@ -0,0 +1,129 @@
+package useCase
+
+import (
+	"context"
+	"errors"
+	"go-auth/modules/auth/model"
+	"log"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// providerTokenData binds a sealed token to its user and provider, so it cannot be
+// opened as the token of anybody else
+func providerTokenData(userId string, provider string) []byte {
+	return []byte(userId + ":" + provider)
+}
+
+// saveProviderToken seals the token of the provider and keeps it for the user
+func (u *authUsecase) saveProviderToken(userId string, provider string, token *oauth2.Token) error {
+	if token == nil || token.AccessToken == "" {
+		return nil
+	}
+
+	additionalData := providerTokenData(userId, provider)
+
+	accessToken, err := u.tokenVault.Seal([]byte(token.AccessToken), additionalData)
+	if err != nil {
+		return err
+	}
+
+	var refreshToken string
+	if token.RefreshToken != "" {
+		refreshToken, err = u.tokenVault.Seal([]byte(token.RefreshToken), additionalData)
+		if err != nil {
+			return err
+		}
+	}
+
+	return u.authRepository.SaveProviderToken(&model.ProviderToken{
+		UserId:       userId,
+		Provider:     provider,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    token.Type(),
+		Expiry:       token.Expiry,
+		UpdatedAt:    time.Now(),
+	})
+}
+
+// ProviderToken returns a token to call the API of the provider as the user. An expired
+// token is refreshed first, and the new one is kept. A token that cannot be refreshed
+// means the user has to log in with the provider again.
+func (u *authUsecase) ProviderToken(userId string, providerName string) (*model.ProviderTokenRes, error) {
+	provider, err := u.socialProviders.Provider(providerName)
+	if err != nil {
+		return nil, model.ErrUnknownLoginProvider
+	}
+
+	// Without a token vault nothing was ever stored
+	if u.tokenVault == nil {
+		return nil, model.ErrProviderTokenNotFound
+	}
+
+	stored, err := u.authRepository.FindProviderToken(userId, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	additionalData := providerTokenData(userId, providerName)
+
+	accessToken, err := u.tokenVault.Open(stored.AccessToken, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &oauth2.Token{
+		AccessToken: string(accessToken),
+		TokenType:   stored.TokenType,
+		Expiry:      stored.Expiry,
+	}
+
+	if stored.RefreshToken != "" {
+		refreshToken, err := u.tokenVault.Open(stored.RefreshToken, additionalData)
+		if err != nil {
+			return nil, err
+		}
+		token.RefreshToken = string(refreshToken)
+	}
+
+	if !token.Valid() && token.RefreshToken == "" {
+		return nil, model.ErrProviderTokenExpired
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	source, err := provider.TokenSource(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh, err := source.Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			log.Printf("Error: Refresh %s token failed: %s", providerName, err.Error())
+			return nil, model.ErrProviderTokenExpired
+		}
+		return nil, err
+	}
+
+	// Keep what the refresh returned, and move tokens of a retired key to the active one
+	if fresh.AccessToken != token.AccessToken || u.tokenVault.NeedsReseal(stored.AccessToken) {
+		if err := u.saveProviderToken(userId, providerName, fresh); err != nil {
+			log.Printf("Error: Save %s token failed: %s", providerName, err.Error())
+		}
+	}
+
+	tokenRes := &model.ProviderTokenRes{
+		AccessToken: fresh.AccessToken,
+		TokenType:   fresh.Type(),
+	}
+	if !fresh.Expiry.IsZero() {
+		tokenRes.ExpiresAt = &fresh.Expiry
+	}
+
+	return tokenRes, nil
+}
@@ -0,0 +1,133 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// authenticateRevocationCaller returns the client a revocation request comes from. Our own
+// frontends send no client and may only revoke tokens that were issued to no client.
+func (u *authUsecase) authenticateRevocationCaller(clientId string, clientSecret string) (string, error) {
+	if clientId == "" {
+		return "", nil
+	}
+
+	if strings.HasPrefix(clientId, serviceAccountIdPrefix) {
+		account, err := u.authenticateServiceAccount(clientId, clientSecret)
+		if err != nil {
+			return "", err
+		}
+		return account.ClientId, nil
+	}
+
+	client, err := u.authenticateClient(clientId, clientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	return client.ClientId, nil
+}
+
+// RevokeToken revokes any kind of token of the caller. Following RFC 7009 section 2.2,
+// unknown, invalid and foreign tokens are ignored, so the answer never tells whether a
+// token was valid.
+func (u *authUsecase) RevokeToken(cfg *config.Config, revokeReq *model.RevokeReq) error {
+	clientId, err := u.authenticateRevocationCaller(revokeReq.ClientId, revokeReq.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	if revokeReq.Token == "" {
+		return model.ErrOAuthInvalidRequest("Missing token")
+	}
+
+	if strings.HasPrefix(revokeReq.Token, jwtAuth.ApiKeyPrefix) {
+		if clientId != "" {
+			return nil
+		}
+		return u.revokeApiKeyByValue(cfg, revokeReq.Token)
+	}
+
+	// The hint only decides what is tried first
+	if revokeReq.TokenTypeHint == "refresh_token" {
+		if revoked, err := u.revokeRefreshToken(cfg, revokeReq.Token, clientId); revoked || err != nil {
+			return err
+		}
+		_, err := u.revokeAccessToken(cfg, revokeReq.Token, clientId)
+		return err
+	}
+
+	if revoked, err := u.revokeAccessToken(cfg, revokeReq.Token, clientId); revoked || err != nil {
+		return err
+	}
+	_, err = u.revokeRefreshToken(cfg, revokeReq.Token, clientId)
+	return err
+}
+
+func (u *authUsecase) revokeApiKeyByValue(cfg *config.Config, key string) error {
+	apiKey, _, err := u.apiKeyClaims(cfg, key)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidApiKey) {
+			return nil
+		}
+		return err
+	}
+
+	if err := u.authRepository.RevokeApiKey(apiKey.UserId, apiKey.ID); err != nil && !errors.Is(err, model.ErrApiKeyNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// revokeAccessToken blocks the jti of the access token until it expires. It reports false
+// when the token is no access token of the client.
+func (u *authUsecase) revokeAccessToken(cfg *config.Config, token string, clientId string) (bool, error) {
+	claims, err := jwtAuth.ParseToken(cfg.Jwt.AccessTokenSecret, token)
+	if err != nil || claims.Claims == nil || claims.ExpiresAt == nil {
+		return false, nil
+	}
+
+	if claims.Subject != "access-token" && claims.Subject != "service-token" {
+		return false, nil
+	}
+
+	if claims.ClientId != clientId {
+		return false, nil
+	}
+
+	return true, u.revocation.Revoke(claims.ID, claims.ExpiresAt.Time)
+}
+
+// revokeRefreshToken ends the session of the refresh token, which takes the whole token
+// family down with it. It reports false when the token is no refresh token of the client.
+func (u *authUsecase) revokeRefreshToken(cfg *config.Config, token string, clientId string) (bool, error) {
+	claims := &jwtAuth.AuthMapClaims{}
+	refreshToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.Jwt.RefreshTokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !refreshToken.Valid || claims.Claims == nil || claims.Subject != "refresh-token" {
+		return false, nil
+	}
+
+	if claims.ClientId != clientId {
+		return false, nil
+	}
+
+	if err := u.revocation.Revoke(claims.ID, claims.ExpiresAt.Time); err != nil {
+		return true, model.ErrAddBlacklistTokenFailed
+	}
+
+	if claims.FamilyId != "" {
+		if err := u.revokeFamily(claims.FamilyId); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
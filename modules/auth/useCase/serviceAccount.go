@@ -0,0 +1,133 @@
+package useCase
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/secureToken"
+	"strings"
+	"time"
+)
+
+// Service account ids are told apart from OAuth client ids by their prefix
+const serviceAccountIdPrefix = "sa_"
+
+func (u *authUsecase) CreateServiceAccount(createReq *model.CreateServiceAccountReq) (*model.ServiceAccountSecretRes, error) {
+	secret, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &model.ServiceAccount{
+		ClientId:        serviceAccountIdPrefix + jwtAuth.NewTokenId(),
+		SecretHash:      secureToken.HashToken(secret),
+		Name:            createReq.Name,
+		Scopes:          createReq.Scopes,
+		SecretRotatedAt: time.Now(),
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if err := u.oauthRepository.AddServiceAccount(account); err != nil {
+		return nil, err
+	}
+
+	return &model.ServiceAccountSecretRes{
+		ServiceAccount: account,
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (u *authUsecase) ListServiceAccounts() ([]*model.ServiceAccount, error) {
+	return u.oauthRepository.FindServiceAccounts()
+}
+
+func (u *authUsecase) RotateServiceAccountSecret(cfg *config.Config, clientId string) (*model.ServiceAccountSecretRes, error) {
+	secret, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	gracePeriod := time.Duration(cfg.Jwt.ServiceAccountSecretGracePeriod) * time.Minute
+
+	account, err := u.oauthRepository.RotateServiceAccountSecret(clientId, secureToken.HashToken(secret), time.Now().Add(gracePeriod))
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ServiceAccountSecretRes{
+		ServiceAccount: account,
+		ClientSecret:   secret,
+	}, nil
+}
+
+func (u *authUsecase) DisableServiceAccount(clientId string) error {
+	return u.oauthRepository.DisableServiceAccount(clientId)
+}
+
+// authenticateServiceAccount accepts the current secret, or the previous one while it is
+// still inside its grace period.
+func (u *authUsecase) authenticateServiceAccount(clientId string, clientSecret string) (*model.ServiceAccount, error) {
+	if !strings.HasPrefix(clientId, serviceAccountIdPrefix) || clientSecret == "" {
+		return nil, model.ErrOAuthInvalidClient()
+	}
+
+	account, err := u.oauthRepository.FindServiceAccount(clientId)
+	if err != nil {
+		if errors.Is(err, model.ErrServiceAccountNotFound) {
+			return nil, model.ErrOAuthInvalidClient()
+		}
+		return nil, err
+	}
+
+	if account.Disabled {
+		return nil, model.ErrOAuthInvalidClient()
+	}
+
+	if secureToken.CompareHash(clientSecret, account.SecretHash) {
+		return account, nil
+	}
+
+	if account.PreviousSecretHash != "" && account.PreviousSecretExpiresAt != nil &&
+		time.Now().Before(*account.PreviousSecretExpiresAt) &&
+		secureToken.CompareHash(clientSecret, account.PreviousSecretHash) {
+		return account, nil
+	}
+
+	return nil, model.ErrOAuthInvalidClient()
+}
+
+// clientCredentials issues a service token. There is no refresh token, the account simply
+// asks again with its credentials.
+func (u *authUsecase) clientCredentials(cfg *config.Config, tokenReq *model.TokenReq) (*model.TokenRes, error) {
+	account, err := u.authenticateServiceAccount(tokenReq.ClientId, tokenReq.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := strings.Fields(tokenReq.Scope)
+	if len(scopes) == 0 {
+		scopes = account.Scopes
+	}
+	if !containsAll(account.Scopes, scopes) {
+		return nil, model.ErrOAuthInvalidScope("The service account may not ask for this scope")
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	accessToken, err := jwtAuth.NewServiceToken(cfg.Jwt.AccessTokenSecret, cfg.Jwt.ServiceTokenDuration, &jwtAuth.Claims{
+		ClientId: account.ClientId,
+		Scope:    scope,
+	}).SignToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenRes{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   cfg.Jwt.ServiceTokenDuration * 60,
+		Scope:       scope,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package useCase
+
+import (
+	"go-auth/modules/auth/model"
+)
+
+func (u *authUsecase) ListSessions(userId string, currentSessionId string) ([]*model.Session, error) {
+	sessions, err := u.sessionRepository.FindActiveSessionsByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionId
+	}
+
+	return sessions, nil
+}
+
+func (u *authUsecase) RevokeSession(userId string, sessionId string) error {
+	session, err := u.sessionRepository.FindSession(sessionId)
+	if err != nil {
+		return err
+	}
+
+	// Do not reveal that a session of another user exists
+	if session.UserId != userId {
+		return model.ErrSessionNotFound
+	}
+
+	return u.revokeFamily(session.ID)
+}
+
+func (u *authUsecase) RevokeOtherSessions(userId string, currentSessionId string) error {
+	sessions, err := u.sessionRepository.FindActiveSessionsByUserId(userId)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == currentSessionId {
+			continue
+		}
+
+		if err := u.revokeFamily(session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
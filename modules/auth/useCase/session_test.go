@@ -0,0 +1,221 @@
+package useCase
+
+import (
+	"go-auth/modules/auth/model"
+	"go-auth/modules/auth/repository"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/tokenRevocation"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeSessionRepository keeps sessions in memory
+type fakeSessionRepository struct {
+	repository.SessionRepository
+	sessions map[string]*model.Session
+}
+
+func newFakeSessionRepository(sessions ...*model.Session) *fakeSessionRepository {
+	r := &fakeSessionRepository{sessions: make(map[string]*model.Session)}
+	for _, session := range sessions {
+		r.sessions[session.ID] = session
+	}
+	return r
+}
+
+func (r *fakeSessionRepository) FindSession(sessionId string) (*model.Session, error) {
+	session, ok := r.sessions[sessionId]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return session, nil
+}
+
+func (r *fakeSessionRepository) FindActiveSessionsByUserId(userId string) ([]*model.Session, error) {
+	var sessions []*model.Session
+	for _, session := range r.sessions {
+		if session.UserId == userId && !session.Revoked {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepository) RevokeSession(sessionId string) error {
+	if session, ok := r.sessions[sessionId]; ok {
+		session.Revoked = true
+	}
+	return nil
+}
+
+// memoryRevocationBackend is a tokenRevocation.Backend of a single instance
+type memoryRevocationBackend struct {
+	revoked  map[string]time.Time
+	versions map[string]int64
+}
+
+type memorySubscription chan string
+
+func newRevocationStore() *tokenRevocation.Store {
+	backend := &memoryRevocationBackend{
+		revoked:  make(map[string]time.Time),
+		versions: make(map[string]int64),
+	}
+	return tokenRevocation.New(backend, tokenRevocation.Options{
+		FilterCapacity:      1000,
+		RefreshInterval:     time.Hour,
+		AccessTokenLifetime: 15 * time.Minute,
+	})
+}
+
+func (b *memoryRevocationBackend) EachRevoked(fn func(jti string)) error {
+	for jti := range b.revoked {
+		fn(jti)
+	}
+	return nil
+}
+
+func (b *memoryRevocationBackend) Revoke(jti string, expiresAt time.Time) error {
+	b.revoked[jti] = expiresAt
+	return nil
+}
+
+func (b *memoryRevocationBackend) Publish(jti string) error {
+	return nil
+}
+
+func (b *memoryRevocationBackend) Subscribe() tokenRevocation.Subscription {
+	return make(memorySubscription)
+}
+
+func (b *memoryRevocationBackend) IsRevoked(jti string) (bool, error) {
+	expiresAt, ok := b.revoked[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (b *memoryRevocationBackend) BumpTokenVersion(userId string) (int64, error) {
+	b.versions[userId]++
+	return b.versions[userId], nil
+}
+
+func (b *memoryRevocationBackend) TokenVersion(userId string) (int64, error) {
+	return b.versions[userId], nil
+}
+
+func (s memorySubscription) Revoked() <-chan string {
+	return s
+}
+
+func (s memorySubscription) Close() error {
+	return nil
+}
+
+// fakeFamilyRepository records the refresh token families it revoked
+type fakeFamilyRepository struct {
+	repository.AuthRepository
+	revokedFamilies []string
+}
+
+func (r *fakeFamilyRepository) RevokeRefreshTokenFamily(familyId string) error {
+	r.revokedFamilies = append(r.revokedFamilies, familyId)
+	return nil
+}
+
+func accessTokenClaims(userId string, familyId string) *jwtAuth.AuthMapClaims {
+	claims := &jwtAuth.AuthMapClaims{
+		Claims: &jwtAuth.Claims{UserId: userId, FamilyId: familyId},
+	}
+	claims.Subject = "access-token"
+	claims.ID = jwtAuth.NewTokenId()
+	return claims
+}
+
+func TestRevokeSession(t *testing.T) {
+	const userId = "user-1"
+
+	tests := []struct {
+		name        string
+		userId      string
+		sessionId   string
+		wantErr     error
+		wantRevoked map[string]bool
+	}{
+		{
+			name:        "own session",
+			userId:      userId,
+			sessionId:   "phone",
+			wantRevoked: map[string]bool{"phone": true, "laptop": false},
+		},
+		{
+			name:        "session of another user",
+			userId:      "user-2",
+			sessionId:   "phone",
+			wantErr:     model.ErrSessionNotFound,
+			wantRevoked: map[string]bool{"phone": false, "laptop": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := newFakeSessionRepository(
+				&model.Session{ID: "phone", UserId: userId},
+				&model.Session{ID: "laptop", UserId: userId},
+			)
+			u := &authUsecase{
+				authRepository:    &fakeFamilyRepository{},
+				sessionRepository: sessions,
+				revocation:        newRevocationStore(),
+			}
+
+			if err := u.RevokeSession(tt.userId, tt.sessionId); err != tt.wantErr {
+				t.Fatalf("RevokeSession() error = %v, want %v", err, tt.wantErr)
+			}
+
+			for sessionId, want := range tt.wantRevoked {
+				if got := sessions.sessions[sessionId].Revoked; got != want {
+					t.Errorf("session %s revoked = %v, want %v", sessionId, got, want)
+				}
+				// The access token of the device stops working with its session
+				if got := u.revocation.IsRevoked(accessTokenClaims(userId, sessionId)); got != want {
+					t.Errorf("access token of %s revoked = %v, want %v", sessionId, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	const userId = "user-1"
+
+	sessions := newFakeSessionRepository(
+		&model.Session{ID: "current", UserId: userId},
+		&model.Session{ID: "phone", UserId: userId},
+		&model.Session{ID: "laptop", UserId: userId},
+	)
+	u := &authUsecase{
+		authRepository:    &fakeFamilyRepository{},
+		sessionRepository: sessions,
+		revocation:        newRevocationStore(),
+	}
+
+	if err := u.RevokeOtherSessions(userId, "current"); err != nil {
+		t.Fatalf("RevokeOtherSessions() error = %v", err)
+	}
+
+	tests := []struct {
+		sessionId string
+		want      bool
+	}{
+		{sessionId: "current", want: false},
+		{sessionId: "phone", want: true},
+		{sessionId: "laptop", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := u.revocation.IsRevoked(accessTokenClaims(userId, tt.sessionId)); got != tt.want {
+			t.Errorf("access token of %s revoked = %v, want %v", tt.sessionId, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package useCase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/cookieHelper"
+	"go-auth/pkg/oauth"
+	"go-auth/pkg/secureToken"
+	"log"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/oauth2"
+)
+
+// BeginSocialLogin returns the url of the provider to send the browser to. The state goes
+// into a cookie, and the nonce and code verifier stay here until the callback.
+func (u *authUsecase) BeginSocialLogin(c echo.Context, cfg *config.Config, providerName string) (string, error) {
+	return u.beginSocialLogin(c, cfg, providerName, "")
+}
+
+// beginSocialLogin starts a login, or a link to the account of linkUserId when it is set
+func (u *authUsecase) beginSocialLogin(c echo.Context, cfg *config.Config, providerName string, linkUserId string) (string, error) {
+	provider, err := u.socialProviders.Provider(providerName)
+	if err != nil {
+		return "", model.ErrUnknownLoginProvider
+	}
+
+	authReq, err := oauth.NewAuthRequest()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	redirectUrl, err := provider.AuthCodeURL(ctx, authReq)
+	if err != nil {
+		log.Printf("Error: Start %s login failed: %s", providerName, err.Error())
+		return "", model.ErrSocialLoginFailed
+	}
+
+	state := &model.SocialLoginState{
+		Provider:     providerName,
+		Nonce:        authReq.Nonce,
+		CodeVerifier: authReq.CodeVerifier,
+		LinkUserId:   linkUserId,
+	}
+	ttl := time.Duration(cfg.SocialLogin.StateDuration) * time.Second
+
+	if err := u.authRepository.AddSocialLoginState(secureToken.HashToken(authReq.State), state, ttl); err != nil {
+		return "", err
+	}
+
+	cookieHelper.NewCookieHelper(c, cfg).SetSocialLoginState(authReq.State, provider.ResponseMode() == "form_post")
+
+	return redirectUrl, nil
+}
+
+// FinishSocialLogin signs in the user the provider vouches for, registering them on their
+// first login, or links the identity when the login was started to link it. The state has
+// to come back in the cookie of the browser that started the login, and is used up either
+// way.
+func (u *authUsecase) FinishSocialLogin(c echo.Context, cfg *config.Config, providerName string, callbackReq *model.SocialCallbackReq) (*model.SocialLoginRes, error) {
+	provider, err := u.socialProviders.Provider(providerName)
+	if err != nil {
+		return nil, model.ErrUnknownLoginProvider
+	}
+
+	cookie := cookieHelper.NewCookieHelper(c, cfg)
+	cookieState := cookie.SocialLoginState()
+	cookie.ClearSocialLoginState()
+
+	if cookieState == "" || subtle.ConstantTimeCompare([]byte(cookieState), []byte(callbackReq.State)) != 1 {
+		return nil, model.ErrInvalidSocialLoginState
+	}
+
+	state, err := u.authRepository.TakeSocialLoginState(secureToken.HashToken(callbackReq.State))
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Provider != providerName {
+		return nil, model.ErrInvalidSocialLoginState
+	}
+
+	// The user turned the provider down, or the provider failed
+	if callbackReq.Error != "" || callbackReq.Code == "" {
+		log.Printf("Error: %s login was not completed: %s %s", providerName, callbackReq.Error, callbackReq.ErrorDescription)
+		return nil, model.ErrSocialLoginFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identity, token, err := provider.Exchange(ctx, callbackReq.Code, &oauth.AuthRequest{
+		State:        callbackReq.State,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+	})
+	if err != nil {
+		log.Printf("Error: Finish %s login failed: %s", providerName, err.Error())
+		return nil, model.ErrSocialLoginFailed
+	}
+
+	if state.LinkUserId != "" {
+		linked, err := u.linkIdentity(c, state.LinkUserId, identity)
+		if err != nil {
+			return nil, err
+		}
+
+		u.keepProviderToken(state.LinkUserId, providerName, token)
+
+		return &model.SocialLoginRes{Identity: linked}, nil
+	}
+
+	user, err := u.findOrRegisterSocialUser(c, cfg, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	u.keepProviderToken(user.ID.Hex(), providerName, token)
+
+	accessToken, err := u.signIn(c, cfg, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SocialLoginRes{AccessToken: accessToken}, nil
+}
+
+// keepProviderToken saves the token of the provider for later calls to its API. Losing
+// it only costs those calls, so the login goes on.
+func (u *authUsecase) keepProviderToken(userId string, provider string, token *oauth2.Token) {
+	if u.tokenVault == nil {
+		return
+	}
+
+	if err := u.saveProviderToken(userId, provider, token); err != nil {
+		log.Printf("Error: Save %s token failed: %s", provider, err.Error())
+	}
+}
+
+// SocialIdTokenLogin signs in the user of an ID token the client got from the provider
+// directly, without a redirect through us.
+func (u *authUsecase) SocialIdTokenLogin(c echo.Context, cfg *config.Config, providerName string, idTokenReq *model.SocialIdTokenReq) (*model.AccessToken, error) {
+	provider, err := u.socialProviders.Provider(providerName)
+	if err != nil {
+		return nil, model.ErrUnknownLoginProvider
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	identity, err := provider.VerifyIdToken(ctx, idTokenReq.IdToken, idTokenReq.Nonce)
+	if errors.Is(err, oauth.ErrIdTokenNotSupported) {
+		return nil, model.ErrIdTokenLoginNotSupported
+	} else if err != nil {
+		log.Printf("Error: Verify %s id token failed: %s", providerName, err.Error())
+		return nil, model.ErrSocialLoginFailed
+	}
+
+	user, err := u.findOrRegisterSocialUser(c, cfg, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.signIn(c, cfg, user)
+}
+
+// findOrRegisterSocialUser returns the user of the identity, and registers one on the
+// first login. An account with the same email gets the identity linked when both the
+// provider and the account have verified the email. Otherwise it is refused, as whoever
+// registered the account first may not own the email, and only the owner of that account
+// may link it.
+func (u *authUsecase) findOrRegisterSocialUser(c echo.Context, cfg *config.Config, identity *oauth.Identity) (*model.User, error) {
+	user, err := u.authRepository.FindByProviderId(identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, model.ErrSocialEmailRequired
+	}
+
+	existing, err := u.authRepository.FindOneUserByEmail(identity.Email)
+	if err == nil {
+		if !identity.EmailVerified || !existing.EmailVerified {
+			return nil, model.ErrEmailAlreadyExists
+		}
+
+		if _, err := u.linkIdentity(c, existing.ID.Hex(), identity); err != nil {
+			return nil, err
+		}
+
+		return existing, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	userPassport := &model.UserPassport{
+		Email:         identity.Email,
+		OauthProvider: identity.Provider,
+		OauthId:       identity.Subject,
+		EmailVerified: identity.EmailVerified,
+		Role:          "user",
+	}
+
+	newUser, err := u.authRepository.AddUser(userPassport)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, model.ErrEmailAlreadyExists
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Providers that do not vouch for the email leave it to us
+	if !newUser.EmailVerified {
+		if err := u.sendVerificationEmail(cfg, newUser); err != nil {
+			log.Printf("Error: Send verification email failed: %s", err.Error())
+		}
+	}
+
+	return newUser, nil
+}
+
+// signIn finishes a login of a user that proved who they are, asking for the second factor
+// first when the account has one.
+func (u *authUsecase) signIn(c echo.Context, cfg *config.Config, user *model.User) (*model.AccessToken, error) {
+	if !user.EmailVerified && cfg.EmailVerification.RequiredFor(user.Role) {
+		return nil, model.ErrEmailNotVerified
+	}
+
+	if user.MfaEnabled {
+		return u.mfaChallenge(cfg, user)
+	}
+
+	tokens, err := u.GenerateTokens(c, user, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := cookieHelper.NewCookieHelper(c, cfg)
+
+	cookie.SetRefreshToken(tokens.RefreshToken)
+
+	return &model.AccessToken{
+		AccessToken: tokens.AccessToken,
+		IdToken:     tokens.IdToken,
+	}, nil
+}
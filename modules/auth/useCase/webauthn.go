@@ -0,0 +1,284 @@
+package useCase
+
+import (
+	"encoding/json"
+	"errors"
+	"go-auth/config"
+	"go-auth/modules/auth/model"
+	"go-auth/pkg/cookieHelper"
+	"io"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const webauthnSessionDuration = 5 * time.Minute
+
+// webauthnUser adapts model.User to the user the WebAuthn library works with. The user
+// handle is the raw ObjectID, so a discoverable login leads straight to the account.
+type webauthnUser struct {
+	*model.User
+}
+
+func (w *webauthnUser) WebAuthnID() []byte {
+	return w.ID[:]
+}
+
+func (w *webauthnUser) WebAuthnName() string {
+	return w.Email
+}
+
+func (w *webauthnUser) WebAuthnDisplayName() string {
+	return w.Email
+}
+
+func (w *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (w *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(w.Passkeys))
+
+	for _, passkey := range w.Passkeys {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+		for _, transport := range passkey.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialId,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+func (u *authUsecase) saveWebauthnSession(key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return u.authRepository.SaveWebauthnSession(key, data, webauthnSessionDuration)
+}
+
+func (u *authUsecase) takeWebauthnSession(key string) (*webauthn.SessionData, error) {
+	data, err := u.authRepository.TakeWebauthnSession(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, model.ErrInvalidWebauthnSession
+	}
+
+	return &session, nil
+}
+
+func (u *authUsecase) BeginPasskeyRegistration(userId string) (*protocol.CredentialCreation, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	wUser := &webauthnUser{user}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Passkeys))
+	for _, credential := range wUser.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := u.webAuthn.BeginRegistration(wUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.saveWebauthnSession("registration:"+userId, session); err != nil {
+		return nil, err
+	}
+
+	return creation, nil
+}
+
+func (u *authUsecase) FinishPasskeyRegistration(userId string, name string, body io.Reader) (*model.Passkey, error) {
+	user, err := u.findUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := u.takeWebauthnSession("registration:" + userId)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return nil, model.ErrInvalidPasskey
+	}
+
+	credential, err := u.webAuthn.CreateCredential(&webauthnUser{user}, *session, parsed)
+	if err != nil {
+		return nil, model.ErrInvalidPasskey
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := &model.Passkey{
+		CredentialId:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		Name:            name,
+		CreatedAt:       time.Now(),
+	}
+
+	if err := u.authRepository.AddPasskey(user.ID, passkey); err != nil {
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+// BeginPasskeyLogin starts an assertion for the account of the email, or a discoverable
+// one where the authenticator picks the account when no email is given.
+func (u *authUsecase) BeginPasskeyLogin(loginReq *model.WebauthnLoginReq) (*protocol.CredentialAssertion, error) {
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+		err       error
+	)
+
+	if loginReq.Email != "" {
+		user, findErr := u.authRepository.FindOneUserByEmail(loginReq.Email)
+		if findErr == mongo.ErrNoDocuments || (findErr == nil && len(user.Passkeys) == 0) {
+			return nil, model.ErrInvalidPasskey
+		} else if findErr != nil {
+			return nil, findErr
+		}
+
+		assertion, session, err = u.webAuthn.BeginLogin(&webauthnUser{user})
+	} else {
+		assertion, session, err = u.webAuthn.BeginDiscoverableLogin()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.saveWebauthnSession("login:"+session.Challenge, session); err != nil {
+		return nil, err
+	}
+
+	return assertion, nil
+}
+
+// FinishPasskeyLogin verifies the assertion and signs the user in with the same token pair
+// as a password login.
+func (u *authUsecase) FinishPasskeyLogin(c echo.Context, cfg *config.Config, body io.Reader) (*model.AccessToken, error) {
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return nil, model.ErrInvalidPasskey
+	}
+
+	session, err := u.takeWebauthnSession("login:" + parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+
+	if len(session.UserID) > 0 {
+		user, err = u.authRepository.FindUserByUID(primitive.ObjectID(session.UserID))
+		if err != nil {
+			return nil, model.ErrInvalidPasskey
+		}
+
+		_, err = u.webAuthn.ValidateLogin(&webauthnUser{user}, *session, parsed)
+	} else {
+		_, err = u.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			if len(userHandle) != len(primitive.ObjectID{}) {
+				return nil, errors.New("invalid user handle")
+			}
+
+			user, err = u.authRepository.FindUserByUID(primitive.ObjectID(userHandle))
+			if err != nil {
+				return nil, err
+			}
+
+			return &webauthnUser{user}, nil
+		}, *session, parsed)
+	}
+	if err != nil || user == nil {
+		return nil, model.ErrInvalidPasskey
+	}
+
+	return u.completePasskeyLogin(c, cfg, user, parsed)
+}
+
+func (u *authUsecase) completePasskeyLogin(c echo.Context, cfg *config.Config, user *model.User, parsed *protocol.ParsedCredentialAssertionData) (*model.AccessToken, error) {
+	var passkey *model.Passkey
+	for i := range user.Passkeys {
+		if string(user.Passkeys[i].CredentialId) == string(parsed.RawID) {
+			passkey = &user.Passkeys[i]
+			break
+		}
+	}
+	if passkey == nil {
+		return nil, model.ErrInvalidPasskey
+	}
+
+	// A counter that does not move forward means a second copy of the key may be in use.
+	// Authenticators that do not count at all always report zero.
+	signCount := parsed.Response.AuthenticatorData.Counter
+	if (signCount != 0 || passkey.SignCount != 0) && signCount <= passkey.SignCount {
+		u.recordSecurityEvent(c, user.ID.Hex(), model.SecurityEventPasskeyCloneWarning)
+		return nil, model.ErrInvalidPasskey
+	}
+
+	if err := u.authRepository.UpdatePasskeySignCount(user.ID, passkey.CredentialId, signCount); err != nil {
+		return nil, err
+	}
+
+	if !user.EmailVerified && cfg.EmailVerification.RequiredFor(user.Role) {
+		return nil, model.ErrEmailNotVerified
+	}
+
+	tokens, err := u.GenerateTokens(c, user, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := cookieHelper.NewCookieHelper(c, cfg)
+
+	cookie.SetRefreshToken(tokens.RefreshToken)
+
+	return &model.AccessToken{
+		AccessToken: tokens.AccessToken,
+		IdToken:     tokens.IdToken,
+	}, nil
+}
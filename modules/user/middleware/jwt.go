@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"go-auth/pkg/jwtAuth"
+	"net/http"
 	"os"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -10,10 +11,25 @@ import (
 )
 
 func JWTMiddleware() echo.MiddlewareFunc {
-	return echoJwt.WithConfig(echoJwt.Config{
-		SigningKey: []byte(os.Getenv("ACCESS_TOKEN_SECRET")), // Replace with your actual secret key
+	jwtMiddleware := echoJwt.WithConfig(echoJwt.Config{
+		// HS256 tokens are checked with the secret, asymmetric ones with the key named by their kid
+		KeyFunc: jwtAuth.Keyfunc(os.Getenv("ACCESS_TOKEN_SECRET")),
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(jwtAuth.AuthMapClaims)
 		},
 	})
+
+	// Tokens of clients and revoked tokens are turned away even though their signature and
+	// expiry are fine
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return jwtMiddleware(func(c echo.Context) error {
+			if userJwt, ok := c.Get("user").(*jwt.Token); ok {
+				claims, ok := userJwt.Claims.(*jwtAuth.AuthMapClaims)
+				if !ok || !jwtAuth.IsAccessToken(claims) || jwtAuth.IsRevoked(claims) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt")
+				}
+			}
+			return next(c)
+		})
+	}
 }
@@ -11,6 +11,10 @@ import (
 type (
 	Cookie interface {
 		SetRefreshToken(refreshToken string)
+		ClearRefreshToken()
+		SetSocialLoginState(state string, crossSite bool)
+		SocialLoginState() string
+		ClearSocialLoginState()
 	}
 
 	cookie struct {
@@ -37,3 +41,60 @@ func (c *cookie) SetRefreshToken(refreshToken string) {
 
 	c.Context.SetCookie(refreshTokenCookie)
 }
+
+func (c *cookie) ClearRefreshToken() {
+	refreshTokenCookie := &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     "/",
+	}
+
+	c.Context.SetCookie(refreshTokenCookie)
+}
+
+// SetSocialLoginState binds a social login to the browser that started it. SameSite Lax
+// still sends it on the top level redirect back from the provider. A provider that posts
+// the callback from its own site needs SameSite None, which browsers only take with Secure.
+func (c *cookie) SetSocialLoginState(state string, crossSite bool) {
+	stateCookie := &http.Cookie{
+		Name:     "social_login_state",
+		Value:    state,
+		MaxAge:   int(c.Cfg.SocialLogin.StateDuration),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/auth",
+	}
+
+	if crossSite {
+		stateCookie.SameSite = http.SameSiteNoneMode
+		stateCookie.Secure = true
+	}
+
+	c.Context.SetCookie(stateCookie)
+}
+
+func (c *cookie) SocialLoginState() string {
+	stateCookie, err := c.Context.Cookie("social_login_state")
+	if err != nil {
+		return ""
+	}
+
+	return stateCookie.Value
+}
+
+func (c *cookie) ClearSocialLoginState() {
+	stateCookie := &http.Cookie{
+		Name:     "social_login_state",
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/auth",
+	}
+
+	c.Context.SetCookie(stateCookie)
+}
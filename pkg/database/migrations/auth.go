@@ -22,10 +22,29 @@ func AuthMigrate(pctx context.Context, cfg *config.Config) {
 	// Users collection
 	col := db.Collection("Users")
 
+	// Accounts from before email verification never had the chance to verify, turning
+	// the requirement on must not lock them out
+	if _, err := col.UpdateMany(pctx, bson.M{"email_verified": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"email_verified": true}}); err != nil {
+		log.Fatalf("Error backfilling email_verified: %v", err)
+	}
+
+	// Social accounts used to be the oauth_provider and oauth_id of the user. They are
+	// the first entry of the identities now, which accounts can have several of.
+	backfill := mongo.Pipeline{{{Key: "$set", Value: bson.M{"identities": bson.A{bson.M{
+		"provider":  "$oauth_provider",
+		"subject":   "$oauth_id",
+		"email":     "$email",
+		"linked_at": "$created_at",
+	}}}}}}
+	if _, err := col.UpdateMany(pctx, bson.M{"oauth_id": bson.M{"$gt": ""}, "identities": bson.M{"$exists": false}}, backfill); err != nil {
+		log.Fatalf("Error moving social accounts to identities: %v", err)
+	}
+
 	// Create indexes for Users collection
 	indexes, err := col.Indexes().CreateMany(pctx, []mongo.IndexModel{
-		{Keys: bson.D{{"email", 1}}, Options: options.Index().SetUnique(true)},
-		// {Keys: bson.D{{"oauth_provider", 1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "passkeys.credential_id", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}})},
 		// {Keys: bson.D{{"role", 1}}},
 	})
 	if err != nil {
@@ -38,10 +57,16 @@ func AuthMigrate(pctx context.Context, cfg *config.Config) {
 	// Blacklist collection
 	col = db.Collection("Blacklists")
 
+	// Entries used to hold the whole refresh token. They cannot be matched by jti, and the
+	// rotation records in RefreshTokens still catch a reused token, so they can go.
+	if _, err := col.DeleteMany(pctx, bson.M{"jti": bson.M{"$exists": false}}); err != nil {
+		log.Fatalf("Error removing legacy blacklist entries: %v", err)
+	}
+
 	// Create indexes for Blacklist collection
 	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
-		// {Keys: bson.D{{"refresh_token", 1}}},
-		// {Keys: bson.D{{"expires_at", 1}}},
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
 	})
 	if err != nil {
 		log.Fatalf("Error creating indexes for blacklist collection: %v", err)
@@ -50,5 +75,153 @@ func AuthMigrate(pctx context.Context, cfg *config.Config) {
 		log.Printf("Created index: %s", index)
 	}
 
-	log.Println("User and Blacklist migrations completed successfully")
+	// RefreshTokens collection
+	col = db.Collection("RefreshTokens")
+
+	// Create indexes for RefreshTokens collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "family_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for refresh tokens collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// ProviderTokens collection
+	col = db.Collection("ProviderTokens")
+
+	// Create indexes for ProviderTokens collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "provider", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for provider tokens collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// SecurityEvents collection
+	col = db.Collection("SecurityEvents")
+
+	// Create indexes for SecurityEvents collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for security events collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// Sessions collection
+	col = db.Collection("Sessions")
+
+	// Create indexes for Sessions collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_used_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for sessions collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// PasswordResets collection
+	col = db.Collection("PasswordResets")
+
+	// Create indexes for PasswordResets collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for password resets collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// EmailVerifications collection
+	col = db.Collection("EmailVerifications")
+
+	// Create indexes for EmailVerifications collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for email verifications collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// OAuthClients collection
+	col = db.Collection("OAuthClients")
+
+	// Create indexes for OAuthClients collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for oauth clients collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// OAuthConsents collection
+	col = db.Collection("OAuthConsents")
+
+	// Create indexes for OAuthConsents collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for oauth consents collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// ServiceAccounts collection
+	col = db.Collection("ServiceAccounts")
+
+	// Create indexes for ServiceAccounts collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "client_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for service accounts collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	// ApiKeys collection
+	col = db.Collection("ApiKeys")
+
+	// Create indexes for ApiKeys collection
+	indexes, err = col.Indexes().CreateMany(pctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		log.Fatalf("Error creating indexes for api keys collection: %v", err)
+	}
+	for _, index := range indexes {
+		log.Printf("Created index: %s", index)
+	}
+
+	log.Println("Auth migrations completed successfully")
 }
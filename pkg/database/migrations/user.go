@@ -24,7 +24,7 @@ func UserMigrate(pctx context.Context, cfg *config.Config) {
 
 	// Create indexes for Users collection
 	indexes, err := col.Indexes().CreateMany(pctx, []mongo.IndexModel{
-		{Keys: bson.D{{"uid", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "uid", Value: 1}}, Options: options.Index().SetUnique(true)},
 	})
 	if err != nil {
 		log.Fatalf("Error creating indexes for users collection: %v", err)
@@ -0,0 +1,51 @@
+package jwtAuth
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrNoSigningKey = errors.New("error: id tokens need an asymmetric signing key")
+
+type (
+	// IdTokenClaims are the OpenID Connect claims of an ID token
+	IdTokenClaims struct {
+		Email         string           `json:"email,omitempty"`
+		EmailVerified bool             `json:"email_verified"`
+		AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
+		Nonce         string           `json:"nonce,omitempty"`
+		jwt.RegisteredClaims
+	}
+)
+
+// NewIdToken signs an ID token for the user with the active key of the key store.
+// expiredAt is in minutes, like access tokens.
+func NewIdToken(userId string, audience string, expiredAt int64, authTime time.Time, claims *IdTokenClaims) (string, error) {
+	ks := GetKeyStore()
+	if ks == nil {
+		return "", ErrNoSigningKey
+	}
+
+	key, err := ks.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        NewTokenId(),
+		Issuer:    os.Getenv("JWT_ISSUER"),
+		Subject:   userId,
+		Audience:  jwt.ClaimStrings{audience},
+		ExpiresAt: JwtTimeDurationMinute(expiredAt),
+		IssuedAt:  jwt.NewNumericDate(now()),
+	}
+	claims.AuthTime = jwt.NewNumericDate(authTime)
+
+	token := jwt.NewWithClaims(key.Method(), claims)
+	token.Header["kid"] = key.Kid
+
+	return token.SignedString(key.Private)
+}
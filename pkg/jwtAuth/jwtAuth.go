@@ -1,6 +1,8 @@
 package jwtAuth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -8,14 +10,31 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ApiKeyPrefix starts every personal API key, so leaked keys are easy to search for
+const ApiKeyPrefix = "gak_"
+
+const (
+	// AudienceClient is the audience of the access tokens third-party OAuth clients get
+	// for a user. Access tokens of the service itself carry no audience.
+	AudienceClient = "oauth-client"
+	// AudienceService is the audience of the tokens of service accounts
+	AudienceService = "service"
+)
+
 type (
 	AuthFactory interface {
-		SignToken() string
+		SignToken() (string, error)
+		GetClaims() *AuthMapClaims
 	}
 
 	Claims struct {
-		UserId   string `json:"user_id"`
-		RoleCode string `json:"role_code"`
+		UserId        string `json:"user_id"`
+		RoleCode      string `json:"role_code"`
+		EmailVerified bool   `json:"email_verified"`
+		FamilyId      string `json:"fid,omitempty"`
+		ClientId      string `json:"client_id,omitempty"`
+		Scope         string `json:"scope,omitempty"`
+		TokenVersion  int64  `json:"tv,omitempty"`
 	}
 
 	AuthMapClaims struct {
@@ -25,6 +44,8 @@ type (
 
 	authConcrete struct {
 		Secret []byte
+		Key    *SigningKey
+		KeyErr error
 		Claims *AuthMapClaims `json:"claims"`
 	}
 
@@ -36,19 +57,39 @@ type (
 		*authConcrete
 	}
 
-	apiKey struct {
+	serviceToken struct {
 		*authConcrete
 	}
 )
 
-func (a *authConcrete) SignToken() string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, a.Claims)
-	ss, err := token.SignedString(a.Secret)
-	if err != nil {
+// SignToken fails when the key store could not hand out a signing key, rather than
+// falling back to the shared secret.
+func (a *authConcrete) SignToken() (string, error) {
+	if a.KeyErr != nil {
+		return "", a.KeyErr
+	}
 
+	if a.Key != nil {
+		token := jwt.NewWithClaims(a.Key.Method(), a.Claims)
+		token.Header["kid"] = a.Key.Kid
+		return token.SignedString(a.Key.Private)
 	}
 
-	return ss
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, a.Claims)
+	return token.SignedString(a.Secret)
+}
+
+func (a *authConcrete) GetClaims() *AuthMapClaims {
+	return a.Claims
+}
+
+// NewTokenId returns a random identifier suitable for a jti or a token family id
+func NewTokenId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
 }
 
 func now() time.Time {
@@ -72,15 +113,32 @@ func jwtTimeRepeatAdapter(t int64) *jwt.NumericDate {
 	return jwt.NewNumericDate(time.Unix(t, 0))
 }
 
+// NewAccessToken signs with the active key of the key store when one is set, and with the
+// shared secret otherwise. Tokens for a third-party client get the AudienceClient audience.
 func NewAccessToken(secret string, expiredAt int64, claims *Claims) AuthFactory {
+	var key *SigningKey
+	var keyErr error
+	if ks := GetKeyStore(); ks != nil {
+		key, keyErr = ks.SigningKey()
+	}
+
+	var audience jwt.ClaimStrings
+	if claims.ClientId != "" {
+		audience = jwt.ClaimStrings{AudienceClient}
+	}
+
 	return &accessToken{
 		authConcrete: &authConcrete{
 			Secret: []byte(secret),
+			Key:    key,
+			KeyErr: keyErr,
 			Claims: &AuthMapClaims{
 				Claims: claims,
 				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        NewTokenId(),
 					Issuer:    os.Getenv("JWT_ISSUER"),
 					Subject:   "access-token",
+					Audience:  audience,
 					ExpiresAt: JwtTimeDurationMinute(expiredAt),
 					NotBefore: jwt.NewNumericDate(now()),
 					IssuedAt:  jwt.NewNumericDate(now()),
@@ -97,6 +155,7 @@ func NewRefreshToken(secret string, expiredAt int64, claims *Claims) AuthFactory
 			Claims: &AuthMapClaims{
 				Claims: claims,
 				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        NewTokenId(),
 					Issuer:    os.Getenv("JWT_ISSUER"),
 					Subject:   "refresh-token",
 					ExpiresAt: JwtTimeDurationHour(expiredAt),
@@ -108,16 +167,28 @@ func NewRefreshToken(secret string, expiredAt int64, claims *Claims) AuthFactory
 	}
 }
 
-func NewApiKey(secret string, expiredAt int64) AuthFactory {
-	return &apiKey{
+// NewServiceToken signs an access token for a service account. It carries the client and
+// its scopes, but no user or role.
+func NewServiceToken(secret string, expiredAt int64, claims *Claims) AuthFactory {
+	var key *SigningKey
+	var keyErr error
+	if ks := GetKeyStore(); ks != nil {
+		key, keyErr = ks.SigningKey()
+	}
+
+	return &serviceToken{
 		authConcrete: &authConcrete{
 			Secret: []byte(secret),
+			Key:    key,
+			KeyErr: keyErr,
 			Claims: &AuthMapClaims{
-				Claims: &Claims{},
+				Claims: claims,
 				RegisteredClaims: jwt.RegisteredClaims{
+					ID:        NewTokenId(),
 					Issuer:    os.Getenv("JWT_ISSUER"),
-					Subject:   "api-key",
-					ExpiresAt: JwtTimeDurationHour(expiredAt),
+					Subject:   "service-token",
+					Audience:  jwt.ClaimStrings{AudienceService},
+					ExpiresAt: JwtTimeDurationMinute(expiredAt),
 					NotBefore: jwt.NewNumericDate(now()),
 					IssuedAt:  jwt.NewNumericDate(now()),
 				},
@@ -126,13 +197,37 @@ func NewApiKey(secret string, expiredAt int64) AuthFactory {
 	}
 }
 
-func ParseToken(secret string, tokenString string) (*AuthMapClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AuthMapClaims{}, func(token *jwt.Token) (interface{}, error) {
+// IsAccessToken reports whether the claims are of an access token users got from the
+// service itself, or of a third-party client or service account token issued for one of
+// audiences. ID tokens and refresh tokens are never access tokens.
+func IsAccessToken(claims *AuthMapClaims, audiences ...string) bool {
+	if claims == nil || claims.Claims == nil {
+		return false
+	}
+
+	if claims.ClientId == "" {
+		return claims.Subject == "access-token" && len(claims.Audience) == 0
+	}
 
-		return []byte(secret), nil
-	})
+	if claims.Subject != "access-token" && claims.Subject != "service-token" {
+		return false
+	}
+
+	for _, audience := range audiences {
+		for _, granted := range claims.Audience {
+			if granted == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func ParseToken(secret string, tokenString string) (*AuthMapClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AuthMapClaims{}, Keyfunc(secret))
 
-	if !token.Valid {
+	if token == nil || !token.Valid {
 		if errors.Is(err, jwt.ErrTokenMalformed) {
 			return nil, errors.New("error: token format is invalid")
 		} else if errors.Is(err, jwt.ErrTokenExpired) {
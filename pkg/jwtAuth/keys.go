@@ -0,0 +1,403 @@
+package jwtAuth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKeyId        = errors.New("error: unknown key id")
+	ErrUnsupportedKeyType  = errors.New("error: unsupported key type")
+	ErrAlgorithmMismatched = errors.New("error: token algorithm does not match the key")
+)
+
+type (
+	// SigningKey is an asymmetric key that signs access tokens. Its public half is
+	// published in the JWKS so other services can verify tokens without a shared secret.
+	SigningKey struct {
+		Kid     string
+		Alg     string
+		Private crypto.Signer
+	}
+
+	// KeyStore holds the signing keys of the issuer
+	KeyStore interface {
+		// SigningKey returns the key new tokens are signed with
+		SigningKey() (*SigningKey, error)
+		// VerificationKey returns the key with the kid to verify a token with
+		VerificationKey(kid string) (*SigningKey, error)
+		// PublicKeys returns every key to publish in the JWKS
+		PublicKeys() []*SigningKey
+	}
+
+	JWK struct {
+		Kty string `json:"kty"`
+		Use string `json:"use,omitempty"`
+		Kid string `json:"kid"`
+		Alg string `json:"alg"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+		Y   string `json:"y,omitempty"`
+	}
+
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+
+	staticKeyStore struct {
+		key *SigningKey
+	}
+)
+
+var (
+	keyStoreMu sync.RWMutex
+	keyStore   KeyStore
+)
+
+// SetKeyStore makes access tokens signed and verified with asymmetric keys. Without a key
+// store they are signed with the shared HS256 secret.
+func SetKeyStore(ks KeyStore) {
+	keyStoreMu.Lock()
+	defer keyStoreMu.Unlock()
+	keyStore = ks
+}
+
+func GetKeyStore() KeyStore {
+	keyStoreMu.RLock()
+	defer keyStoreMu.RUnlock()
+	return keyStore
+}
+
+func NewStaticKeyStore(key *SigningKey) KeyStore {
+	return &staticKeyStore{key: key}
+}
+
+func (s *staticKeyStore) SigningKey() (*SigningKey, error) {
+	return s.key, nil
+}
+
+func (s *staticKeyStore) VerificationKey(kid string) (*SigningKey, error) {
+	if kid != s.key.Kid {
+		return nil, ErrUnknownKeyId
+	}
+	return s.key, nil
+}
+
+func (s *staticKeyStore) PublicKeys() []*SigningKey {
+	return []*SigningKey{s.key}
+}
+
+func (k *SigningKey) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Alg)
+}
+
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// JWK returns the public half of the key
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{
+		Use: "sig",
+		Kid: k.Kid,
+		Alg: k.Alg,
+	}
+
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the key, used as its default kid
+func (k *SigningKey) Thumbprint() string {
+	jwk := k.JWK()
+
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PublicKey decodes the key, such as one from the JWKS of another issuer
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKeyType
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, ErrUnsupportedKeyType
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKeyType
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, ErrUnsupportedKeyType
+}
+
+func PublicJWKS(ks KeyStore) *JWKS {
+	jwks := &JWKS{Keys: make([]JWK, 0)}
+	if ks == nil {
+		return jwks
+	}
+
+	for _, key := range ks.PublicKeys() {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+	return jwks
+}
+
+func checkKeyAlg(alg string, signer crypto.Signer) error {
+	var ok bool
+	switch alg {
+	case AlgRS256:
+		_, ok = signer.(*rsa.PrivateKey)
+	case AlgES256:
+		var ec *ecdsa.PrivateKey
+		ec, ok = signer.(*ecdsa.PrivateKey)
+		ok = ok && ec.Curve == elliptic.P256()
+	case AlgEdDSA:
+		_, ok = signer.(ed25519.PrivateKey)
+	}
+
+	if !ok {
+		return fmt.Errorf("error: key does not fit algorithm %s", alg)
+	}
+	return nil
+}
+
+// GenerateSigningKey creates a new key for the algorithm, identified by its thumbprint
+func GenerateSigningKey(alg string) (*SigningKey, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+
+	switch alg {
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{Alg: alg, Private: signer}
+	key.Kid = key.Thumbprint()
+	return key, nil
+}
+
+// ParseSigningKey reads a PEM encoded PKCS#8, PKCS#1 or SEC 1 private key
+func ParseSigningKey(kid string, alg string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("error: private key is not PEM encoded")
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKeyType
+	}
+
+	if err := checkKeyAlg(alg, signer); err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{Kid: kid, Alg: alg, Private: signer}
+	if key.Kid == "" {
+		key.Kid = key.Thumbprint()
+	}
+	return key, nil
+}
+
+// MarshalSigningKey encodes the private key as PKCS#8 PEM
+func MarshalSigningKey(key *SigningKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// LoadKeyStore returns the key store for the configured algorithm, or nil for HS256.
+// An asymmetric algorithm without a key file gets a throwaway key, which is only
+// good enough for local development since every restart logs everybody out.
+func LoadKeyStore(alg string, keyPath string, kid string) (KeyStore, error) {
+	if alg == "" || alg == AlgHS256 {
+		return nil, nil
+	}
+
+	if keyPath == "" {
+		log.Printf("Warning: JWT_PRIVATE_KEY_PATH is empty, using a generated %s key", alg)
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			return nil, err
+		}
+		return NewStaticKeyStore(key), nil
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParseSigningKey(kid, alg, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStaticKeyStore(key), nil
+}
+
+// Keyfunc verifies HS256 tokens with the secret and asymmetric ones with the key of the
+// key store named by the kid header. Once a key store is set HS256 tokens are turned
+// away, so whoever holds the shared secret cannot mint access tokens anymore.
+func Keyfunc(secret string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		ks := GetKeyStore()
+
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			if ks != nil {
+				return nil, ErrAlgorithmMismatched
+			}
+			if secret == "" {
+				return nil, ErrUnknownKeyId
+			}
+			return []byte(secret), nil
+		}
+
+		if ks == nil {
+			return nil, ErrUnknownKeyId
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := ks.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Alg != token.Method.Alg() {
+			return nil, ErrAlgorithmMismatched
+		}
+
+		return key.Public(), nil
+	}
+}
@@ -0,0 +1,35 @@
+package jwtAuth
+
+import "sync"
+
+// RevocationList knows the access tokens that were revoked before they expired
+type RevocationList interface {
+	IsRevoked(claims *AuthMapClaims) bool
+}
+
+var (
+	revocationListMu sync.RWMutex
+	revocationList   RevocationList
+)
+
+func SetRevocationList(rl RevocationList) {
+	revocationListMu.Lock()
+	defer revocationListMu.Unlock()
+	revocationList = rl
+}
+
+func GetRevocationList() RevocationList {
+	revocationListMu.RLock()
+	defer revocationListMu.RUnlock()
+	return revocationList
+}
+
+// IsRevoked reports whether the token was revoked. Without a revocation list every token
+// is good until it expires.
+func IsRevoked(claims *AuthMapClaims) bool {
+	rl := GetRevocationList()
+	if rl == nil || claims == nil {
+		return false
+	}
+	return rl.IsRevoked(claims)
+}
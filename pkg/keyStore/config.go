@@ -0,0 +1,72 @@
+package keyStore
+
+import (
+	"errors"
+	"go-auth/config"
+	"go-auth/pkg/jwtAuth"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewFromConfig returns the rotating key store selected by JWT_KEY_STORE_DRIVER. Retired
+// keys are kept for as long as the longest lived token they signed.
+func NewFromConfig(cfg *config.Config, db *mongo.Client) (*RotatingKeyStore, error) {
+	backend, opts, err := fromConfig(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRotatingKeyStore(backend, opts)
+}
+
+// OpenFromConfig is NewFromConfig without the rotation of keys that are due, so that a
+// manual rotation moves the keys exactly one step.
+func OpenFromConfig(cfg *config.Config, db *mongo.Client) (*RotatingKeyStore, error) {
+	backend, opts, err := fromConfig(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenRotatingKeyStore(backend, opts)
+}
+
+func fromConfig(cfg *config.Config, db *mongo.Client) (Backend, Options, error) {
+	var backend Backend
+	switch cfg.Jwt.KeyStoreDriver {
+	case "file":
+		backend = NewFileBackend(cfg.Jwt.KeyStorePath)
+	case "mongo":
+		backend = NewMongoBackend(db)
+	default:
+		return nil, Options{}, errors.New("error: unknown key store driver " + cfg.Jwt.KeyStoreDriver)
+	}
+
+	alg := cfg.Jwt.SigningAlg
+	switch alg {
+	case "", jwtAuth.AlgHS256:
+		alg = jwtAuth.AlgES256
+	case jwtAuth.AlgRS256, jwtAuth.AlgES256, jwtAuth.AlgEdDSA:
+	default:
+		return nil, Options{}, errors.New("error: unsupported signing algorithm " + alg)
+	}
+
+	if cfg.Jwt.KeyRefreshInterval <= 0 {
+		return nil, Options{}, errors.New("error: JWT_KEY_REFRESH_INTERVAL must be positive")
+	}
+	if cfg.Jwt.KeyRotationInterval <= 0 {
+		return nil, Options{}, errors.New("error: JWT_KEY_ROTATION_INTERVAL must be positive")
+	}
+
+	refreshInterval := time.Duration(cfg.Jwt.KeyRefreshInterval) * time.Second
+
+	// Access, ID and service tokens are all signed with these keys, in minutes
+	tokenLifetime := max(cfg.Jwt.AccessTokenDuration, cfg.Oidc.IdTokenDuration, cfg.Jwt.ServiceTokenDuration)
+
+	return backend, Options{
+		Alg:              alg,
+		RotationInterval: time.Duration(cfg.Jwt.KeyRotationInterval) * time.Hour,
+		RetentionPeriod:  time.Duration(tokenLifetime)*time.Minute + refreshInterval,
+		RefreshInterval:  refreshInterval,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package keyStore
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type fileBackend struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileBackend keeps the key set in a JSON file. The version check only guards against
+// writers in the same process, so use it for a single instance.
+func NewFileBackend(path string) Backend {
+	return &fileBackend{path: path}
+}
+
+func (b *fileBackend) load() (*KeySet, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &KeySet{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+func (b *fileBackend) Load() (*KeySet, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.load()
+}
+
+func (b *fileBackend) Save(set *KeySet, expectedVersion int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, err := b.load()
+	if err != nil {
+		return err
+	}
+
+	if current.Version != expectedVersion {
+		return ErrConflict
+	}
+
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write and rename so that a crash never leaves a half written key file
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".keys-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), b.path)
+}
@@ -0,0 +1,352 @@
+package keyStore
+
+import (
+	"errors"
+	"go-auth/pkg/jwtAuth"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// StateNext keys are published in the JWKS but do not sign yet, so verifiers have
+	// cached them by the time they become active
+	StateNext = "next"
+	// StateActive is the single key that signs new tokens
+	StateActive = "active"
+	// StateRetired keys no longer sign but still verify tokens until RetireAt
+	StateRetired = "retired"
+)
+
+var ErrConflict = errors.New("error: key set was changed by someone else")
+
+type (
+	KeyRecord struct {
+		Kid         string     `bson:"kid" json:"kid"`
+		Alg         string     `bson:"alg" json:"alg"`
+		PrivateKey  string     `bson:"private_key" json:"private_key"`
+		State       string     `bson:"state" json:"state"`
+		CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+		ActivatedAt *time.Time `bson:"activated_at,omitempty" json:"activated_at,omitempty"`
+		RetireAt    *time.Time `bson:"retire_at,omitempty" json:"retire_at,omitempty"`
+	}
+
+	KeySet struct {
+		Version int64        `bson:"version" json:"version"`
+		Keys    []*KeyRecord `bson:"keys" json:"keys"`
+	}
+
+	// Backend persists the key set. Save must fail with ErrConflict when the stored
+	// version is not expectedVersion, so two instances never rotate at the same time.
+	Backend interface {
+		Load() (*KeySet, error)
+		Save(set *KeySet, expectedVersion int64) error
+	}
+
+	Options struct {
+		Alg string
+		// RotationInterval is how long a key stays active
+		RotationInterval time.Duration
+		// RetentionPeriod is how long a retired key keeps verifying, at least the
+		// lifetime of the longest token it signed
+		RetentionPeriod time.Duration
+		// RefreshInterval is how often the key set is reloaded and checked for rotation
+		RefreshInterval time.Duration
+	}
+
+	// RotatingKeyStore is a jwtAuth.KeyStore with an active, a next and any number of
+	// retired keys
+	RotatingKeyStore struct {
+		backend Backend
+		opts    Options
+
+		mu      sync.RWMutex
+		version int64
+		active  *jwtAuth.SigningKey
+		keys    map[string]*jwtAuth.SigningKey
+		records []*KeyRecord
+
+		stop chan struct{}
+	}
+)
+
+func NewRotatingKeyStore(backend Backend, opts Options) (*RotatingKeyStore, error) {
+	s := &RotatingKeyStore{
+		backend: backend,
+		opts:    opts,
+		stop:    make(chan struct{}),
+	}
+
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// OpenRotatingKeyStore loads the key set as it is, without creating or rotating keys, for
+// tools that change the keys themselves.
+func OpenRotatingKeyStore(backend Backend, opts Options) (*RotatingKeyStore, error) {
+	s := &RotatingKeyStore{
+		backend: backend,
+		opts:    opts,
+		stop:    make(chan struct{}),
+	}
+
+	set, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.apply(set); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *RotatingKeyStore) SigningKey() (*jwtAuth.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.active == nil {
+		return nil, jwtAuth.ErrUnknownKeyId
+	}
+	return s.active, nil
+}
+
+func (s *RotatingKeyStore) VerificationKey(kid string) (*jwtAuth.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, jwtAuth.ErrUnknownKeyId
+	}
+	return key, nil
+}
+
+func (s *RotatingKeyStore) PublicKeys() []*jwtAuth.SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]*jwtAuth.SigningKey, 0, len(s.records))
+	for _, record := range s.records {
+		if key, ok := s.keys[record.Kid]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Records returns a copy of the key records without the private keys
+func (s *RotatingKeyStore) Records() []KeyRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]KeyRecord, 0, len(s.records))
+	for _, record := range s.records {
+		r := *record
+		r.PrivateKey = ""
+		records = append(records, r)
+	}
+	return records
+}
+
+// Refresh reloads the key set, creating the first keys or rotating when they are due.
+func (s *RotatingKeyStore) Refresh() error {
+	set, err := s.backend.Load()
+	if err != nil {
+		return err
+	}
+
+	if due(set, s.opts.RotationInterval) {
+		next, err := rotate(set, s.opts, false)
+		if err != nil {
+			return err
+		}
+
+		err = s.save(set, next)
+		if err == ErrConflict {
+			// Another instance rotated first, take its keys
+			return s.Refresh()
+		}
+		return err
+	}
+
+	return s.apply(set)
+}
+
+// Rotate activates the next key now. An emergency rotation drops the active key at once
+// instead of retiring it, so every token it signed stops verifying.
+func (s *RotatingKeyStore) Rotate(emergency bool) error {
+	set, err := s.backend.Load()
+	if err != nil {
+		return err
+	}
+
+	next, err := rotate(set, s.opts, emergency)
+	if err != nil {
+		return err
+	}
+
+	return s.save(set, next)
+}
+
+// Start refreshes the key set in the background until Stop is called
+func (s *RotatingKeyStore) Start() {
+	go func() {
+		ticker := time.NewTicker(s.opts.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Refresh(); err != nil {
+					log.Printf("Error: Refresh signing keys failed: %s", err.Error())
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *RotatingKeyStore) Stop() {
+	close(s.stop)
+}
+
+func (s *RotatingKeyStore) save(set *KeySet, next *KeySet) error {
+	if err := s.backend.Save(next, set.Version); err != nil {
+		return err
+	}
+	return s.apply(next)
+}
+
+func (s *RotatingKeyStore) apply(set *KeySet) error {
+	keys := make(map[string]*jwtAuth.SigningKey, len(set.Keys))
+	records := make([]*KeyRecord, 0, len(set.Keys))
+
+	var active *jwtAuth.SigningKey
+	for _, record := range set.Keys {
+		if record.State == StateRetired && record.RetireAt != nil && time.Now().After(*record.RetireAt) {
+			continue
+		}
+
+		key, err := jwtAuth.ParseSigningKey(record.Kid, record.Alg, []byte(record.PrivateKey))
+		if err != nil {
+			return err
+		}
+
+		keys[record.Kid] = key
+		records = append(records, record)
+		if record.State == StateActive {
+			active = key
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.version = set.Version
+	s.active = active
+	s.keys = keys
+	s.records = records
+
+	return nil
+}
+
+func findState(set *KeySet, state string) *KeyRecord {
+	for _, record := range set.Keys {
+		if record.State == state {
+			return record
+		}
+	}
+	return nil
+}
+
+func due(set *KeySet, interval time.Duration) bool {
+	active := findState(set, StateActive)
+	if active == nil || findState(set, StateNext) == nil {
+		return true
+	}
+
+	return active.ActivatedAt == nil || time.Since(*active.ActivatedAt) >= interval
+}
+
+func newRecord(alg string, state string) (*KeyRecord, error) {
+	key, err := jwtAuth.GenerateSigningKey(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := jwtAuth.MarshalSigningKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &KeyRecord{
+		Kid:        key.Kid,
+		Alg:        alg,
+		PrivateKey: string(data),
+		State:      state,
+		CreatedAt:  now,
+	}
+	if state == StateActive {
+		record.ActivatedAt = &now
+	}
+	return record, nil
+}
+
+// rotate returns the key set after one rotation: next becomes active, active becomes
+// retired (or is dropped in an emergency), expired keys go and a new next key is made.
+func rotate(set *KeySet, opts Options, emergency bool) (*KeySet, error) {
+	now := time.Now()
+	retireAt := now.Add(opts.RetentionPeriod)
+
+	next := &KeySet{
+		Version: set.Version + 1,
+		Keys:    make([]*KeyRecord, 0, len(set.Keys)+1),
+	}
+
+	var promoted bool
+	for _, record := range set.Keys {
+		r := *record
+
+		switch r.State {
+		case StateRetired:
+			if r.RetireAt != nil && now.After(*r.RetireAt) {
+				continue
+			}
+		case StateActive:
+			if emergency {
+				continue
+			}
+			r.State = StateRetired
+			r.RetireAt = &retireAt
+		case StateNext:
+			r.State = StateActive
+			r.ActivatedAt = &now
+			promoted = true
+		}
+
+		next.Keys = append(next.Keys, &r)
+	}
+
+	if !promoted {
+		active, err := newRecord(opts.Alg, StateActive)
+		if err != nil {
+			return nil, err
+		}
+		next.Keys = append(next.Keys, active)
+	}
+
+	upcoming, err := newRecord(opts.Alg, StateNext)
+	if err != nil {
+		return nil, err
+	}
+	next.Keys = append(next.Keys, upcoming)
+
+	return next, nil
+}
@@ -0,0 +1,309 @@
+package keyStore
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend keeps the key set in memory. beforeSave runs once before the next Save, to
+// let another instance write in between.
+type fakeBackend struct {
+	mu         sync.Mutex
+	set        *KeySet
+	saves      int
+	beforeSave func()
+}
+
+func (b *fakeBackend) Load() (*KeySet, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return copySet(b.set), nil
+}
+
+func (b *fakeBackend) Save(set *KeySet, expectedVersion int64) error {
+	if beforeSave := b.beforeSave; beforeSave != nil {
+		b.beforeSave = nil
+		beforeSave()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.set.Version != expectedVersion {
+		return ErrConflict
+	}
+	b.set = copySet(set)
+	b.saves++
+	return nil
+}
+
+func copySet(set *KeySet) *KeySet {
+	c := &KeySet{Version: set.Version}
+	for _, record := range set.Keys {
+		r := *record
+		c.Keys = append(c.Keys, &r)
+	}
+	return c
+}
+
+var testOptions = Options{
+	Alg:              jwtAuth.AlgES256,
+	RotationInterval: 24 * time.Hour,
+	RetentionPeriod:  time.Hour,
+	RefreshInterval:  time.Minute,
+}
+
+// newTestKeySet returns a settled key set: an active key activated `age` ago, a next key
+// and a retired key retiring at retireAt
+func newTestKeySet(t *testing.T, age time.Duration, retireAt time.Time) *KeySet {
+	t.Helper()
+
+	set := &KeySet{Version: 3}
+	for _, state := range []string{StateRetired, StateActive, StateNext} {
+		record, err := newRecord(testOptions.Alg, state)
+		if err != nil {
+			t.Fatalf("newRecord() error = %v", err)
+		}
+
+		switch state {
+		case StateRetired:
+			record.RetireAt = &retireAt
+		case StateActive:
+			activatedAt := time.Now().Add(-age)
+			record.ActivatedAt = &activatedAt
+		}
+		set.Keys = append(set.Keys, record)
+	}
+	return set
+}
+
+func kidsByState(set *KeySet) map[string][]string {
+	kids := make(map[string][]string)
+	for _, record := range set.Keys {
+		kids[record.State] = append(kids[record.State], record.Kid)
+	}
+	return kids
+}
+
+func TestNewRotatingKeyStoreCreatesKeys(t *testing.T) {
+	backend := &fakeBackend{set: &KeySet{}}
+
+	s, err := NewRotatingKeyStore(backend, testOptions)
+	if err != nil {
+		t.Fatalf("NewRotatingKeyStore() error = %v", err)
+	}
+
+	kids := kidsByState(backend.set)
+	if backend.set.Version != 1 || len(kids[StateActive]) != 1 || len(kids[StateNext]) != 1 {
+		t.Fatalf("key set = %+v, want version 1 with an active and a next key", kids)
+	}
+
+	key, err := s.SigningKey()
+	if err != nil {
+		t.Fatalf("SigningKey() error = %v", err)
+	}
+	if key.Kid != kids[StateActive][0] {
+		t.Errorf("SigningKey() = %s, want the active key %s", key.Kid, kids[StateActive][0])
+	}
+	if len(s.PublicKeys()) != 2 {
+		t.Errorf("PublicKeys() = %d keys, want the active and the next", len(s.PublicKeys()))
+	}
+}
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name          string
+		emergency     bool
+		wantOldActive string
+	}{
+		{name: "scheduled", wantOldActive: StateRetired},
+		{name: "emergency", emergency: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newTestKeySet(t, time.Hour, time.Now().Add(time.Hour))
+			before := kidsByState(set)
+			backend := &fakeBackend{set: set}
+
+			s, err := OpenRotatingKeyStore(backend, testOptions)
+			if err != nil {
+				t.Fatalf("OpenRotatingKeyStore() error = %v", err)
+			}
+			if err := s.Rotate(tt.emergency); err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+
+			states := make(map[string]string)
+			for _, record := range backend.set.Keys {
+				states[record.Kid] = record.State
+			}
+
+			if backend.set.Version != set.Version+1 {
+				t.Errorf("version = %d, want %d", backend.set.Version, set.Version+1)
+			}
+			// next → active
+			if got := states[before[StateNext][0]]; got != StateActive {
+				t.Errorf("old next key is %q, want %q", got, StateActive)
+			}
+			// active → retired, or gone in an emergency
+			if got := states[before[StateActive][0]]; got != tt.wantOldActive {
+				t.Errorf("old active key is %q, want %q", got, tt.wantOldActive)
+			}
+			if got := states[before[StateRetired][0]]; got != StateRetired {
+				t.Errorf("retired key is %q, want it kept until it retires", got)
+			}
+			if kids := kidsByState(backend.set); len(kids[StateNext]) != 1 || len(kids[StateActive]) != 1 {
+				t.Errorf("key set = %+v, want one active and one new next key", kids)
+			}
+
+			if key, _ := s.SigningKey(); key == nil || key.Kid != before[StateNext][0] {
+				t.Errorf("SigningKey() = %v, want the old next key", key)
+			}
+			_, err = s.VerificationKey(before[StateActive][0])
+			if verifies := err == nil; verifies != !tt.emergency {
+				t.Errorf("old active key verifies = %v, want %v", verifies, !tt.emergency)
+			}
+		})
+	}
+}
+
+func TestRotateRetiresForRetentionPeriod(t *testing.T) {
+	set := newTestKeySet(t, time.Hour, time.Now().Add(-time.Minute))
+	before := kidsByState(set)
+	backend := &fakeBackend{set: set}
+
+	s, err := OpenRotatingKeyStore(backend, testOptions)
+	if err != nil {
+		t.Fatalf("OpenRotatingKeyStore() error = %v", err)
+	}
+	if err := s.Rotate(false); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	for _, record := range backend.set.Keys {
+		switch record.Kid {
+		case before[StateRetired][0]:
+			t.Errorf("the key past its retirement was kept")
+		case before[StateActive][0]:
+			if record.RetireAt == nil || time.Until(*record.RetireAt) > testOptions.RetentionPeriod {
+				t.Errorf("retired key retires at %v, want within %v", record.RetireAt, testOptions.RetentionPeriod)
+			}
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name       string
+		age        time.Duration
+		wantRotate bool
+	}{
+		{name: "not due", age: time.Hour},
+		{name: "due", age: testOptions.RotationInterval + time.Minute, wantRotate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newTestKeySet(t, tt.age, time.Now().Add(time.Hour))
+			backend := &fakeBackend{set: set}
+
+			if _, err := NewRotatingKeyStore(backend, testOptions); err != nil {
+				t.Fatalf("NewRotatingKeyStore() error = %v", err)
+			}
+
+			if rotated := backend.saves == 1; rotated != tt.wantRotate {
+				t.Errorf("rotated = %v, want %v", rotated, tt.wantRotate)
+			}
+		})
+	}
+}
+
+func TestRefreshDropsExpiredRetiredKeys(t *testing.T) {
+	set := newTestKeySet(t, time.Hour, time.Now().Add(-time.Minute))
+	retired := kidsByState(set)[StateRetired][0]
+
+	s, err := NewRotatingKeyStore(&fakeBackend{set: set}, testOptions)
+	if err != nil {
+		t.Fatalf("NewRotatingKeyStore() error = %v", err)
+	}
+
+	if _, err := s.VerificationKey(retired); err != jwtAuth.ErrUnknownKeyId {
+		t.Errorf("VerificationKey() error = %v, want %v", err, jwtAuth.ErrUnknownKeyId)
+	}
+}
+
+func TestOpenRotatingKeyStoreDoesNotRotate(t *testing.T) {
+	set := newTestKeySet(t, testOptions.RotationInterval+time.Minute, time.Now().Add(time.Hour))
+	backend := &fakeBackend{set: set}
+
+	s, err := OpenRotatingKeyStore(backend, testOptions)
+	if err != nil {
+		t.Fatalf("OpenRotatingKeyStore() error = %v", err)
+	}
+	if backend.saves != 0 {
+		t.Fatalf("OpenRotatingKeyStore() saved %d times, want a due key set left alone", backend.saves)
+	}
+
+	// A manual rotation then moves the keys exactly one step
+	before := kidsByState(set)
+	if err := s.Rotate(false); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if key, _ := s.SigningKey(); key == nil || key.Kid != before[StateNext][0] {
+		t.Errorf("SigningKey() = %v, want the old next key", key)
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	t.Run("refresh takes the keys of the other instance", func(t *testing.T) {
+		set := newTestKeySet(t, testOptions.RotationInterval+time.Minute, time.Now().Add(time.Hour))
+		backend := &fakeBackend{set: set}
+
+		other := newTestKeySet(t, 0, time.Now().Add(time.Hour))
+		other.Version = set.Version + 1
+		backend.beforeSave = func() {
+			backend.mu.Lock()
+			backend.set = other
+			backend.mu.Unlock()
+		}
+
+		s, err := NewRotatingKeyStore(backend, testOptions)
+		if err != nil {
+			t.Fatalf("NewRotatingKeyStore() error = %v", err)
+		}
+
+		if backend.saves != 0 {
+			t.Errorf("saves = %d, want the rotation of the other instance kept", backend.saves)
+		}
+		if key, _ := s.SigningKey(); key == nil || key.Kid != kidsByState(other)[StateActive][0] {
+			t.Errorf("SigningKey() = %v, want the active key of the other instance", key)
+		}
+	})
+
+	t.Run("rotate fails", func(t *testing.T) {
+		set := newTestKeySet(t, time.Hour, time.Now().Add(time.Hour))
+		backend := &fakeBackend{set: set}
+
+		s, err := OpenRotatingKeyStore(backend, testOptions)
+		if err != nil {
+			t.Fatalf("OpenRotatingKeyStore() error = %v", err)
+		}
+
+		backend.beforeSave = func() {
+			backend.mu.Lock()
+			backend.set.Version++
+			backend.mu.Unlock()
+		}
+
+		if err := s.Rotate(false); err != ErrConflict {
+			t.Fatalf("Rotate() error = %v, want %v", err, ErrConflict)
+		}
+		if key, _ := s.SigningKey(); key == nil || key.Kid != kidsByState(set)[StateActive][0] {
+			t.Errorf("SigningKey() = %v, want the keys left as they were", key)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package keyStore
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const keySetId = "signing_keys"
+
+type mongoBackend struct {
+	db *mongo.Client
+}
+
+// NewMongoBackend keeps the key set in one document of Auth.SigningKeys, so every
+// instance sees the same keys and only one of them wins a rotation.
+func NewMongoBackend(db *mongo.Client) Backend {
+	return &mongoBackend{db: db}
+}
+
+func (b *mongoBackend) keyCollection() *mongo.Collection {
+	return b.db.Database("Auth").Collection("SigningKeys")
+}
+
+func (b *mongoBackend) Load() (*KeySet, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var set KeySet
+	err := b.keyCollection().FindOne(ctx, bson.M{"_id": keySetId}).Decode(&set)
+	if err == mongo.ErrNoDocuments {
+		return &KeySet{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+func (b *mongoBackend) Save(set *KeySet, expectedVersion int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	document := bson.M{
+		"_id":     keySetId,
+		"version": set.Version,
+		"keys":    set.Keys,
+	}
+
+	if expectedVersion == 0 {
+		_, err := b.keyCollection().InsertOne(ctx, document)
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	filter := bson.M{"_id": keySetId, "version": expectedVersion}
+	result, err := b.keyCollection().ReplaceOne(ctx, filter, document)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrConflict
+	}
+
+	return nil
+}
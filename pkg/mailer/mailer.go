@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"fmt"
+	"go-auth/config"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	Mailer interface {
+		Send(to string, subject string, body string) error
+	}
+
+	logMailer struct {
+		from string
+	}
+
+	fileMailer struct {
+		from string
+		path string
+		mu   sync.Mutex
+	}
+
+	smtpMailer struct {
+		from    string
+		address string
+		auth    smtp.Auth
+	}
+)
+
+// NewMailer returns the mail sender selected by MAIL_DRIVER. The log and file drivers
+// never deliver anything and are meant for local development.
+func NewMailer(cfg *config.Config) Mailer {
+	switch cfg.Mail.Driver {
+	case "smtp":
+		return &smtpMailer{
+			from:    cfg.Mail.From,
+			address: fmt.Sprintf("%s:%d", cfg.Mail.SmtpHost, cfg.Mail.SmtpPort),
+			auth:    smtp.PlainAuth("", cfg.Mail.SmtpUsername, cfg.Mail.SmtpPassword, cfg.Mail.SmtpHost),
+		}
+	case "file":
+		return &fileMailer{
+			from: cfg.Mail.From,
+			path: cfg.Mail.FilePath,
+		}
+	default:
+		return &logMailer{
+			from: cfg.Mail.From,
+		}
+	}
+}
+
+func message(from string, to string, subject string, body string) string {
+	var sb strings.Builder
+	sb.WriteString("From: " + from + "\r\n")
+	sb.WriteString("To: " + to + "\r\n")
+	sb.WriteString("Subject: " + subject + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(body)
+	sb.WriteString("\r\n")
+	return sb.String()
+}
+
+func (m *logMailer) Send(to string, subject string, body string) error {
+	log.Printf("Mail: from=%s to=%s subject=%q\n%s", m.from, to, subject, body)
+	return nil
+}
+
+func (m *fileMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(message(m.from, to, subject, body) + "\r\n")
+	return err
+}
+
+func (m *smtpMailer) Send(to string, subject string, body string) error {
+	return smtp.SendMail(m.address, m.auth, m.from, []string{to}, []byte(message(m.from, to, subject, body)))
+}
@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"go-auth/config"
+	"go-auth/pkg/jwtAuth"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const appleIssuer = "https://appleid.apple.com"
+
+// Apple accepts client secrets for up to six months. Ours are short lived and renewed an
+// hour before they run out.
+const (
+	appleClientSecretDuration = 24 * time.Hour
+	appleClientSecretRenewal  = time.Hour
+)
+
+// appleClientSecret is the client secret of Sign in with Apple: a JWT about the client,
+// signed with a private key of the team.
+type appleClientSecret struct {
+	teamId   string
+	clientId string
+	key      *jwtAuth.SigningKey
+
+	mu        sync.Mutex
+	secret    string
+	expiresAt time.Time
+}
+
+// NewAppleProvider signs users in with Apple. The client secret is signed with the key
+// at the private key path of the config. A nil key set fetches the keys of Apple.
+func NewAppleProvider(providerCfg *config.SocialProvider, keySet KeySet) (Provider, error) {
+	data, err := os.ReadFile(providerCfg.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwtAuth.ParseSigningKey(providerCfg.KeyId, "ES256", data)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &appleClientSecret{
+		teamId:   providerCfg.TeamId,
+		clientId: providerCfg.ClientId,
+		key:      key,
+	}
+
+	provider := newOidcProvider(providerCfg, keySet, providerCfg.Issuer)
+	provider.clientSecret = secret.Secret
+
+	return provider, nil
+}
+
+func (s *appleClientSecret) Secret() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secret != "" && time.Until(s.expiresAt) > appleClientSecretRenewal {
+		return s.secret, nil
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(appleClientSecretDuration)
+
+	// Apple documents aud as a single string, not the array RegisteredClaims would send
+	token := jwt.NewWithClaims(s.key.Method(), jwt.MapClaims{
+		"iss": s.teamId,
+		"sub": s.clientId,
+		"aud": appleIssuer,
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+	})
+	token.Header["kid"] = s.key.Kid
+
+	secret, err := token.SignedString(s.key.Private)
+	if err != nil {
+		return "", err
+	}
+
+	s.secret = secret
+	s.expiresAt = expiresAt
+
+	return secret, nil
+}
@@ -0,0 +1,71 @@
+package oauth
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Claim names of OpenID Connect, which providers may rename in their claim mapping
+var defaultClaimMapping = map[string]string{
+	"subject":        "sub",
+	"email":          "email",
+	"email_verified": "email_verified",
+	"name":           "name",
+	"picture":        "picture",
+}
+
+// claim looks up a claim by name, where dots step into nested objects
+func claim(claims map[string]interface{}, name string) interface{} {
+	var value interface{} = claims
+	for _, key := range strings.Split(name, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// stringClaim also accepts numbers, which some providers use for ids
+func stringClaim(claims map[string]interface{}, name string) string {
+	switch v := claim(claims, name).(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// boolClaim also accepts "true", which some providers send instead of a boolean
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch v := claim(claims, name).(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+func identityFromClaims(provider string, mapping map[string]string, claims map[string]interface{}) *Identity {
+	name := func(field string) string {
+		if claimName, ok := mapping[field]; ok && claimName != "" {
+			return claimName
+		}
+		return defaultClaimMapping[field]
+	}
+
+	return &Identity{
+		Provider:      provider,
+		Subject:       stringClaim(claims, name("subject")),
+		Email:         stringClaim(claims, name("email")),
+		EmailVerified: boolClaim(claims, name("email_verified")),
+		Name:          stringClaim(claims, name("name")),
+		Picture:       stringClaim(claims, name("picture")),
+	}
+}
@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-auth/config"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+type (
+	// githubProvider is plain OAuth 2.0, except that the email of /user is whatever the
+	// user made public, if anything. The verified primary email comes from /user/emails.
+	githubProvider struct {
+		*oauth2Provider
+		emailsUrl string
+	}
+
+	githubEmail struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+)
+
+func NewGithubProvider(providerCfg *config.SocialProvider) Provider {
+	return &githubProvider{
+		oauth2Provider: newOAuth2Provider(providerCfg),
+		emailsUrl:      strings.TrimSuffix(providerCfg.UserInfoUrl, "/") + "/emails",
+	}
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code string, authReq *AuthRequest) (*Identity, *oauth2.Token, error) {
+	identity, token, err := p.oauth2Provider.Exchange(ctx, code, authReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	email, err := p.primaryEmail(ctx, token.AccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// A public email that is not the verified primary one proves nothing
+	if email != "" {
+		identity.Email = email
+		identity.EmailVerified = true
+	} else {
+		identity.EmailVerified = false
+	}
+
+	return identity, token, nil
+}
+
+// primaryEmail returns the primary email of the user when GitHub verified it
+func (p *githubProvider) primaryEmail(ctx context.Context, accessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.emailsUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error: %s responded with status %d", p.emailsUrl, resp.StatusCode)
+	}
+
+	var emails []githubEmail
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return "", err
+	}
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			return email.Email, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,17 @@
+package oauth
+
+import "go-auth/config"
+
+const googleIssuer = "https://accounts.google.com"
+
+// NewGoogleProvider signs users in with Google. Google issues ID tokens as either
+// spelling of its issuer, so both are accepted. The keys default to the JWKS of Google,
+// tests can hand in their own.
+func NewGoogleProvider(providerCfg *config.SocialProvider, keySet KeySet) Provider {
+	issuers := []string{providerCfg.Issuer}
+	if providerCfg.Issuer == googleIssuer {
+		issuers = append(issuers, "accounts.google.com")
+	}
+
+	return newOidcProvider(providerCfg, keySet, issuers...)
+}
@@ -0,0 +1,138 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"go-auth/pkg/jwtAuth"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An ID token signed by an unknown kid refetches the keys, but not more often than this
+const jwksRefetchInterval = time.Minute
+
+// How long fetched keys are used when the provider does not say
+const jwksCacheDuration = time.Hour
+
+type (
+	// KeySet returns the public key an ID token of a provider was signed with
+	KeySet interface {
+		Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+	}
+
+	// remoteKeySet caches the JWKS of a provider for as long as its Cache-Control allows,
+	// and refetches early when a token names a kid it does not know, which is how a key
+	// rotation shows up.
+	remoteKeySet struct {
+		url string
+
+		mu        sync.Mutex
+		keys      map[string]crypto.PublicKey
+		fetchedAt time.Time
+		expiresAt time.Time
+	}
+
+	// StaticKeySet is a fixed set of keys by kid, for providers whose keys are known ahead
+	// and for tests.
+	StaticKeySet map[string]crypto.PublicKey
+)
+
+func NewRemoteKeySet(url string) KeySet {
+	return &remoteKeySet{
+		url:  url,
+		keys: make(map[string]crypto.PublicKey),
+	}
+}
+
+func (s *remoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, known := s.keys[kid]
+	if known && time.Now().Before(s.expiresAt) {
+		return key, nil
+	}
+
+	if !known && time.Now().Before(s.expiresAt) && time.Since(s.fetchedAt) < jwksRefetchInterval {
+		return nil, fmt.Errorf("error: unknown signing key %q", kid)
+	}
+
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	key, known = s.keys[kid]
+	if !known {
+		return nil, fmt.Errorf("error: unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+func (s *remoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: %s responded with status %d", s.url, resp.StatusCode)
+	}
+
+	var jwks jwtAuth.JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.expiresAt = s.fetchedAt.Add(cacheDuration(resp.Header.Get("Cache-Control")))
+
+	return nil
+}
+
+// cacheDuration reads max-age from a Cache-Control header
+func cacheDuration(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.ToLower(name) != "max-age" {
+			continue
+		}
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return jwksCacheDuration
+}
+
+func (s StaticKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, fmt.Errorf("error: unknown signing key %q", kid)
+	}
+	return key, nil
+}
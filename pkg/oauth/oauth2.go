@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth/config"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// oauth2Provider signs users in with plain OAuth 2.0, asking the user info url of the
+// provider who they are.
+type oauth2Provider struct {
+	name         string
+	config       *oauth2.Config
+	userInfoUrl  string
+	responseMode string
+	claimMapping map[string]string
+}
+
+func NewOAuth2Provider(providerCfg *config.SocialProvider) Provider {
+	return newOAuth2Provider(providerCfg)
+}
+
+func newOAuth2Provider(providerCfg *config.SocialProvider) *oauth2Provider {
+	return &oauth2Provider{
+		name: providerCfg.Name,
+		config: &oauth2.Config{
+			ClientID:     providerCfg.ClientId,
+			ClientSecret: providerCfg.ClientSecret,
+			RedirectURL:  providerCfg.RedirectUrl,
+			Scopes:       providerCfg.Scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  providerCfg.AuthUrl,
+				TokenURL: providerCfg.TokenUrl,
+			},
+		},
+		userInfoUrl:  providerCfg.UserInfoUrl,
+		responseMode: providerCfg.ResponseMode,
+		claimMapping: providerCfg.ClaimMapping,
+	}
+}
+
+func (p *oauth2Provider) Name() string {
+	return p.name
+}
+
+func (p *oauth2Provider) ResponseMode() string {
+	return p.responseMode
+}
+
+func (p *oauth2Provider) AuthCodeURL(ctx context.Context, authReq *AuthRequest) (string, error) {
+	return p.config.AuthCodeURL(authReq.State, authCodeOptions(authReq, p.responseMode)...), nil
+}
+
+func (p *oauth2Provider) Exchange(ctx context.Context, code string, authReq *AuthRequest) (*Identity, *oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(authReq.CodeVerifier))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims, err := fetchUserInfo(ctx, p.userInfoUrl, token.AccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity := identityFromClaims(p.name, p.claimMapping, claims)
+	if identity.Subject == "" {
+		return nil, nil, errors.New("error: user info has no subject")
+	}
+
+	return identity, token, nil
+}
+
+func (p *oauth2Provider) VerifyIdToken(ctx context.Context, rawIdToken string, nonce string) (*Identity, error) {
+	return nil, ErrIdTokenNotSupported
+}
+
+func (p *oauth2Provider) TokenSource(ctx context.Context, token *oauth2.Token) (oauth2.TokenSource, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	return p.config.TokenSource(ctx, token), nil
+}
+
+func fetchUserInfo(ctx context.Context, userInfoUrl string, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: user info responded with status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
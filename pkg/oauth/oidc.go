@@ -0,0 +1,328 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-auth/config"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// Clock skew tolerated on the exp, iat and nbf of an ID token
+const idTokenLeeway = time.Minute
+
+// Signing methods an ID token may use. HS256 would sign with the client secret, which
+// we do not accept, and "none" is never valid.
+var idTokenMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type (
+	discoveryDocument struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JwksUri               string `json:"jwks_uri"`
+	}
+
+	// oidcProvider signs users in with OpenID Connect. The endpoints come from the
+	// discovery document of the issuer unless they are configured, and the identity
+	// comes from the verified ID token.
+	oidcProvider struct {
+		providerCfg *config.SocialProvider
+		// Issuers the provider may put in iss. Some providers use more than one
+		// spelling of the same issuer.
+		issuers []string
+
+		// clientSecret signs the provider in at the token endpoint. It is the configured
+		// secret, unless the provider wants one made up on the spot.
+		clientSecret func() (string, error)
+
+		mu        sync.Mutex
+		discovery *discoveryDocument
+		keySet    KeySet
+	}
+)
+
+// NewOidcProvider returns a provider for the issuer of the config. A nil key set fetches
+// the keys from the jwks url of the issuer.
+func NewOidcProvider(providerCfg *config.SocialProvider, keySet KeySet) Provider {
+	return newOidcProvider(providerCfg, keySet, providerCfg.Issuer)
+}
+
+func newOidcProvider(providerCfg *config.SocialProvider, keySet KeySet, issuers ...string) *oidcProvider {
+	return &oidcProvider{
+		providerCfg: providerCfg,
+		issuers:     issuers,
+		keySet:      keySet,
+		clientSecret: func() (string, error) {
+			return providerCfg.ClientSecret, nil
+		},
+	}
+}
+
+func (p *oidcProvider) Name() string {
+	return p.providerCfg.Name
+}
+
+func (p *oidcProvider) ResponseMode() string {
+	return p.providerCfg.ResponseMode
+}
+
+// discover fetches the discovery document once, filling in endpoints that are not
+// configured. A provider with all of them configured is never asked. A failed fetch is
+// retried on the next login.
+func (p *oidcProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	document := &discoveryDocument{
+		Issuer:                p.providerCfg.Issuer,
+		AuthorizationEndpoint: p.providerCfg.AuthUrl,
+		TokenEndpoint:         p.providerCfg.TokenUrl,
+		UserInfoEndpoint:      p.providerCfg.UserInfoUrl,
+		JwksUri:               p.providerCfg.JwksUrl,
+	}
+
+	if document.AuthorizationEndpoint != "" && document.TokenEndpoint != "" && (document.JwksUri != "" || p.keySet != nil) {
+		p.useDiscovery(document)
+		return document, nil
+	}
+
+	discoveryUrl := strings.TrimSuffix(p.providerCfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJson(ctx, discoveryUrl, document); err != nil {
+		return nil, err
+	}
+
+	// The document must be about the issuer we asked, or it could hand us keys of
+	// another one
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(p.providerCfg.Issuer, "/") {
+		return nil, fmt.Errorf("error: discovery document is for issuer %q", document.Issuer)
+	}
+
+	// Configured endpoints win over discovered ones
+	if p.providerCfg.AuthUrl != "" {
+		document.AuthorizationEndpoint = p.providerCfg.AuthUrl
+	}
+	if p.providerCfg.TokenUrl != "" {
+		document.TokenEndpoint = p.providerCfg.TokenUrl
+	}
+	if p.providerCfg.UserInfoUrl != "" {
+		document.UserInfoEndpoint = p.providerCfg.UserInfoUrl
+	}
+	if p.providerCfg.JwksUrl != "" {
+		document.JwksUri = p.providerCfg.JwksUrl
+	}
+
+	p.useDiscovery(document)
+
+	return document, nil
+}
+
+func (p *oidcProvider) useDiscovery(document *discoveryDocument) {
+	p.discovery = document
+	if p.keySet == nil {
+		p.keySet = NewRemoteKeySet(document.JwksUri)
+	}
+}
+
+func (p *oidcProvider) oauth2Config(document *discoveryDocument, clientSecret string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.providerCfg.ClientId,
+		ClientSecret: clientSecret,
+		RedirectURL:  p.providerCfg.RedirectUrl,
+		Scopes:       p.providerCfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  document.AuthorizationEndpoint,
+			TokenURL: document.TokenEndpoint,
+		},
+	}
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, authReq *AuthRequest) (string, error) {
+	document, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	opts := append(authCodeOptions(authReq, p.providerCfg.ResponseMode), oauth2.SetAuthURLParam("nonce", authReq.Nonce))
+
+	return p.oauth2Config(document, "").AuthCodeURL(authReq.State, opts...), nil
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string, authReq *AuthRequest) (*Identity, *oauth2.Token, error) {
+	document, err := p.discover(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientSecret, err := p.clientSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	token, err := p.oauth2Config(document, clientSecret).Exchange(ctx, code, oauth2.VerifierOption(authReq.CodeVerifier))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rawIdToken, _ := token.Extra("id_token").(string)
+	if rawIdToken == "" {
+		return nil, nil, ErrInvalidIdToken
+	}
+
+	claims, err := p.verify(ctx, rawIdToken, authReq.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Not every provider puts the email into the ID token
+	if stringClaim(claims, p.claimName("email")) == "" && document.UserInfoEndpoint != "" {
+		if err := p.mergeUserInfo(ctx, document.UserInfoEndpoint, token.AccessToken, claims); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return identityFromClaims(p.Name(), p.providerCfg.ClaimMapping, claims), token, nil
+}
+
+func (p *oidcProvider) VerifyIdToken(ctx context.Context, rawIdToken string, nonce string) (*Identity, error) {
+	claims, err := p.verify(ctx, rawIdToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return identityFromClaims(p.Name(), p.providerCfg.ClaimMapping, claims), nil
+}
+
+func (p *oidcProvider) TokenSource(ctx context.Context, token *oauth2.Token) (oauth2.TokenSource, error) {
+	document, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSecret, err := p.clientSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+
+	return p.oauth2Config(document, clientSecret).TokenSource(ctx, token), nil
+}
+
+func (p *oidcProvider) claimName(field string) string {
+	if claimName, ok := p.providerCfg.ClaimMapping[field]; ok && claimName != "" {
+		return claimName
+	}
+	return defaultClaimMapping[field]
+}
+
+// verify checks the signature, issuer, audience, expiry and nonce of an ID token and
+// returns its claims.
+func (p *oidcProvider) verify(ctx context.Context, rawIdToken string, nonce string) (map[string]interface{}, error) {
+	if _, err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(idTokenMethods),
+		jwt.WithAudience(p.providerCfg.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+		jwt.WithJSONNumber(),
+	)
+
+	_, err := parser.ParseWithClaims(rawIdToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keySet.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdToken, err.Error())
+	}
+
+	issuer, _ := claims.GetIssuer()
+	if !p.isIssuer(issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIdToken, issuer)
+	}
+
+	if nonce != "" && stringClaim(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIdToken)
+	}
+
+	// A token for several audiences must say it was issued to us
+	audience, _ := claims.GetAudience()
+	if len(audience) > 1 && stringClaim(claims, "azp") != p.providerCfg.ClientId {
+		return nil, fmt.Errorf("%w: token was issued to another party", ErrInvalidIdToken)
+	}
+
+	if stringClaim(claims, "sub") == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidIdToken)
+	}
+
+	return claims, nil
+}
+
+func (p *oidcProvider) isIssuer(issuer string) bool {
+	for _, expected := range p.issuers {
+		if issuer == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeUserInfo adds the claims of the user info endpoint that the ID token lacks. The
+// subject has to match, or the answer is about somebody else.
+func (p *oidcProvider) mergeUserInfo(ctx context.Context, userInfoUrl string, accessToken string, claims map[string]interface{}) error {
+	userInfo, err := fetchUserInfo(ctx, userInfoUrl, accessToken)
+	if err != nil {
+		return err
+	}
+
+	if stringClaim(userInfo, "sub") != stringClaim(claims, "sub") {
+		return errors.New("error: user info is about another subject")
+	}
+
+	for name, value := range userInfo {
+		if _, ok := claims[name]; !ok {
+			claims[name] = value
+		}
+	}
+
+	return nil
+}
+
+func getJson(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: %s responded with status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,130 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"go-auth/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientId = "client-id"
+	testNonce    = "nonce"
+	testKid      = "kid-1"
+)
+
+func TestOidcProviderVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	provider := NewOidcProvider(&config.SocialProvider{
+		Name:     "test",
+		Issuer:   testIssuer,
+		AuthUrl:  testIssuer + "/authorize",
+		TokenUrl: testIssuer + "/token",
+		ClientId: testClientId,
+	}, StaticKeySet{testKid: &key.PublicKey})
+
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   testIssuer,
+			"aud":   testClientId,
+			"sub":   "user-1",
+			"email": "user@example.com",
+			"nonce": testNonce,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		}
+	}
+	with := func(changes jwt.MapClaims) jwt.MapClaims {
+		claims := validClaims()
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		method  jwt.SigningMethod
+		signer  interface{}
+		kid     string
+		noNonce bool
+		wantErr bool
+	}{
+		{name: "valid", claims: validClaims()},
+		{name: "no nonce expected", claims: with(jwt.MapClaims{"nonce": nil}), noNonce: true},
+		{name: "wrong issuer", claims: with(jwt.MapClaims{"iss": "https://other.example.com"}), wantErr: true},
+		{name: "wrong audience", claims: with(jwt.MapClaims{"aud": "other-client"}), wantErr: true},
+		{name: "wrong nonce", claims: with(jwt.MapClaims{"nonce": "other-nonce"}), wantErr: true},
+		{name: "missing nonce", claims: with(jwt.MapClaims{"nonce": nil}), wantErr: true},
+		{name: "several audiences with our azp", claims: with(jwt.MapClaims{"aud": []string{testClientId, "other-client"}, "azp": testClientId})},
+		{name: "several audiences with another azp", claims: with(jwt.MapClaims{"aud": []string{testClientId, "other-client"}, "azp": "other-client"}), wantErr: true},
+		{name: "several audiences without azp", claims: with(jwt.MapClaims{"aud": []string{testClientId, "other-client"}}), wantErr: true},
+		{name: "expired", claims: with(jwt.MapClaims{"exp": now.Add(-time.Hour).Unix()}), wantErr: true},
+		{name: "expired within leeway", claims: with(jwt.MapClaims{"exp": now.Add(-idTokenLeeway / 2).Unix()})},
+		{name: "no expiry", claims: with(jwt.MapClaims{"exp": nil}), wantErr: true},
+		{name: "issued in the future", claims: with(jwt.MapClaims{"iat": now.Add(time.Hour).Unix()}), wantErr: true},
+		{name: "no subject", claims: with(jwt.MapClaims{"sub": nil}), wantErr: true},
+		{name: "other signing key", claims: validClaims(), signer: otherKey, wantErr: true},
+		{name: "unknown kid", claims: validClaims(), kid: "kid-2", wantErr: true},
+		{name: "signed with HS256", claims: validClaims(), method: jwt.SigningMethodHS256, signer: []byte(testClientId), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, signer, kid, nonce := tt.method, tt.signer, tt.kid, testNonce
+			if method == nil {
+				method = jwt.SigningMethodRS256
+			}
+			if signer == nil {
+				signer = key
+			}
+			if kid == "" {
+				kid = testKid
+			}
+			if tt.noNonce {
+				nonce = ""
+			}
+
+			token := jwt.NewWithClaims(method, tt.claims)
+			token.Header["kid"] = kid
+			rawIdToken, err := token.SignedString(signer)
+			if err != nil {
+				t.Fatalf("SignedString() error = %v", err)
+			}
+
+			identity, err := provider.VerifyIdToken(context.Background(), rawIdToken, nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIdToken) {
+					t.Errorf("VerifyIdToken() error = %v, want %v", err, ErrInvalidIdToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyIdToken() error = %v", err)
+			}
+			if identity.Subject != "user-1" || identity.Email != "user@example.com" {
+				t.Errorf("VerifyIdToken() = %+v, want the subject and email of the token", identity)
+			}
+		})
+	}
+}
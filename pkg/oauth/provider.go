@@ -0,0 +1,132 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"go-auth/config"
+	"go-auth/pkg/secureToken"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var ErrUnknownProvider = errors.New("error: unknown login provider")
+
+var ErrInvalidIdToken = errors.New("error: id token is invalid")
+
+var ErrIdTokenNotSupported = errors.New("error: provider does not issue id tokens")
+
+// Calls to providers must not hang a login forever
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+type (
+	// Identity is who the provider says the user is
+	Identity struct {
+		Provider      string
+		Subject       string
+		Email         string
+		EmailVerified bool
+		Name          string
+		Picture       string
+	}
+
+	// AuthRequest is what a login has to remember from the redirect to the provider
+	// until the callback. State binds the callback to the login, nonce binds the ID token
+	// to it and the code verifier proves that whoever redeems the code also started it.
+	AuthRequest struct {
+		State        string
+		Nonce        string
+		CodeVerifier string
+	}
+
+	Provider interface {
+		Name() string
+		// ResponseMode is "form_post" for providers that post the callback from their own
+		// site, which cookies with SameSite Lax do not survive
+		ResponseMode() string
+		AuthCodeURL(ctx context.Context, authReq *AuthRequest) (string, error)
+		// Exchange redeems the code of the callback. The tokens of the provider are
+		// returned as well, for callers that want to call its API on behalf of the user.
+		Exchange(ctx context.Context, code string, authReq *AuthRequest) (*Identity, *oauth2.Token, error)
+		// VerifyIdToken checks an ID token the client got from the provider itself. An
+		// empty nonce is not checked.
+		VerifyIdToken(ctx context.Context, rawIdToken string, nonce string) (*Identity, error)
+		// TokenSource hands out the token until it expires and then refreshes it, as long
+		// as the provider issued a refresh token
+		TokenSource(ctx context.Context, token *oauth2.Token) (oauth2.TokenSource, error)
+	}
+
+	Registry struct {
+		providers map[string]Provider
+	}
+)
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: make(map[string]Provider)}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+	}
+	return registry
+}
+
+// NewFromConfig returns the providers configured by OAUTH2_PROVIDERS. A provider with an
+// issuer speaks OpenID Connect, any other one plain OAuth 2.0.
+func NewFromConfig(cfg *config.Config) *Registry {
+	providers := make([]Provider, 0)
+	for _, providerCfg := range cfg.SocialLogin.Providers {
+		switch {
+		case providerCfg.Name == "google":
+			providers = append(providers, NewGoogleProvider(providerCfg, nil))
+		case providerCfg.Name == "github":
+			providers = append(providers, NewGithubProvider(providerCfg))
+		case providerCfg.Name == "apple":
+			provider, err := NewAppleProvider(providerCfg, nil)
+			if err != nil {
+				log.Fatalf("Error: Load %s login provider failed: %s", providerCfg.Name, err.Error())
+			}
+			providers = append(providers, provider)
+		case providerCfg.Issuer != "":
+			providers = append(providers, NewOidcProvider(providerCfg, nil))
+		default:
+			providers = append(providers, NewOAuth2Provider(providerCfg))
+		}
+	}
+
+	return NewRegistry(providers...)
+}
+
+func (r *Registry) Provider(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+func authCodeOptions(authReq *AuthRequest, responseMode string) []oauth2.AuthCodeOption {
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(authReq.CodeVerifier)}
+	if responseMode != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("response_mode", responseMode))
+	}
+	return opts
+}
+
+func NewAuthRequest() (*AuthRequest, error) {
+	state, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := secureToken.NewToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}, nil
+}
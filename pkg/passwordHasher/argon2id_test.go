@@ -0,0 +1,146 @@
+package passwordHasher
+
+import (
+	"errors"
+	"testing"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      64,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestArgon2idDecode(t *testing.T) {
+	h := &argon2idHasher{params: testArgon2idParams}
+
+	tests := []struct {
+		name    string
+		hash    string
+		want    Argon2idParams
+		wantErr error
+	}{
+		{
+			name: "valid",
+			hash: "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+			want: Argon2idParams{Memory: 65536, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+		},
+		{
+			name:    "other algorithm",
+			hash:    "$argon2i$v=19$m=65536,t=3,p=2$c2FsdA$a2V5",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "other version",
+			hash:    "$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "missing parameter",
+			hash:    "$argon2id$v=19$m=65536,t=3$c2FsdA$a2V5",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "padded salt",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA==$a2V5",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "empty key",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "too few parts",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA",
+			wantErr: ErrInvalidHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _, _, err := h.decode(tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && *params != tt.want {
+				t.Errorf("decode() params = %+v, want %+v", *params, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgon2idHashVerify(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{name: "same password", password: "correct horse battery staple", want: true},
+		{name: "other password", password: "correct horse battery", want: false},
+		{name: "empty password", password: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Verify(tt.password, hash)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2idParams)
+
+	current, err := h.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	older, err := NewArgon2idHasher(stronger).Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	longer := testArgon2idParams
+	longer.KeyLength = 16
+	shorter, err := NewArgon2idHasher(longer).Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "current parameters", hash: current, want: false},
+		{name: "other iterations", hash: older, want: true},
+		{name: "other key length", hash: shorter, want: true},
+		{name: "malformed", hash: "$argon2id$v=19$broken", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
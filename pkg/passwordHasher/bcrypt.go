@@ -0,0 +1,81 @@
+package passwordHasher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptSha256Prefix marks bcrypt hashes of a pre-hashed password. bcrypt ignores
+// everything past 72 bytes, and refuses such passwords outright, so new hashes are taken
+// of the base64 HMAC-SHA256 of the password instead, which always fits.
+const bcryptSha256Prefix = "$bcrypt-sha256"
+
+// bcrypt hashes carry their own $2a$<cost>$ prefix, which predates PHC but records the
+// algorithm and cost all the same. Pre-hashed ones put bcryptSha256Prefix in front of it.
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptHasher{cost: cost}
+}
+
+func preHash(password string) []byte {
+	mac := hmac.New(sha256.New, []byte(bcryptSha256Prefix))
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(preHash(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return bcryptSha256Prefix + string(hash), nil
+}
+
+func (h *bcryptHasher) Verify(password string, hash string) (bool, error) {
+	secret := []byte(password)
+	if strings.HasPrefix(hash, bcryptSha256Prefix) {
+		hash = strings.TrimPrefix(hash, bcryptSha256Prefix)
+		secret = preHash(password)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), secret)
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// NeedsRehash also reports plain bcrypt hashes, so they are pre-hashed on the next login.
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, bcryptSha256Prefix) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(strings.TrimPrefix(hash, bcryptSha256Prefix)))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func (h *bcryptHasher) Recognizes(hash string) bool {
+	hash = strings.TrimPrefix(hash, bcryptSha256Prefix)
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
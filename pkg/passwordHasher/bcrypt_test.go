@@ -0,0 +1,52 @@
+package passwordHasher
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptVerify(t *testing.T) {
+	h := NewBcryptHasher(bcrypt.MinCost)
+
+	long := strings.Repeat("a", 100)
+	longHash, err := h.Hash(long)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		hash       string
+		want       bool
+		wantRehash bool
+	}{
+		{name: "password past 72 bytes", password: long, hash: longHash, want: true},
+		{name: "same first 72 bytes", password: strings.Repeat("a", 90), hash: longHash, want: false},
+		{name: "plain bcrypt hash", password: "password", hash: string(legacy), want: true, wantRehash: true},
+		{name: "plain bcrypt mismatch", password: "other", hash: string(legacy), want: false, wantRehash: true},
+		{name: "plain bcrypt with a long password", password: long, hash: string(legacy), want: false, wantRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Verify(tt.password, tt.hash)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+			if rehash := h.NeedsRehash(tt.hash); rehash != tt.wantRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+}
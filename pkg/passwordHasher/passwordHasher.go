@@ -0,0 +1,85 @@
+package passwordHasher
+
+import (
+	"errors"
+	"go-auth/config"
+)
+
+var ErrUnknownHashFormat = errors.New("error: unknown password hash format")
+
+var ErrInvalidHash = errors.New("error: password hash is malformed")
+
+type (
+	// PasswordHasher hashes passwords into strings that record the algorithm and its
+	// parameters along with the salt and the hash, so they can be checked later whatever
+	// the current settings are.
+	PasswordHasher interface {
+		Hash(password string) (string, error)
+		Verify(password string, hash string) (bool, error)
+		// NeedsRehash reports whether the hash was made with anything else than the
+		// current algorithm and parameters.
+		NeedsRehash(hash string) bool
+		// Recognizes reports whether the hash is in the format of this hasher
+		Recognizes(hash string) bool
+	}
+
+	// chainHasher hashes with the preferred hasher and verifies with whichever hasher
+	// recognizes the hash, so changing the algorithm does not lock anybody out.
+	chainHasher struct {
+		preferred PasswordHasher
+		hashers   []PasswordHasher
+	}
+)
+
+// NewPasswordHasher hashes new passwords with PASSWORD_HASH_ALGORITHM, argon2id unless
+// told otherwise, and still verifies the hashes of every other supported algorithm.
+func NewPasswordHasher(cfg *config.Config) PasswordHasher {
+	argon2id := NewArgon2idHasher(Argon2idParams{
+		Memory:      uint32(cfg.PasswordHash.Argon2Memory),
+		Iterations:  uint32(cfg.PasswordHash.Argon2Iterations),
+		Parallelism: uint8(cfg.PasswordHash.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	bcrypt := NewBcryptHasher(int(cfg.PasswordHash.BcryptCost))
+
+	switch cfg.PasswordHash.Algorithm {
+	case "bcrypt":
+		return NewChainHasher(bcrypt, argon2id)
+	default:
+		return NewChainHasher(argon2id, bcrypt)
+	}
+}
+
+func NewChainHasher(preferred PasswordHasher, others ...PasswordHasher) PasswordHasher {
+	return &chainHasher{
+		preferred: preferred,
+		hashers:   append([]PasswordHasher{preferred}, others...),
+	}
+}
+
+func (h *chainHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *chainHasher) Verify(password string, hash string) (bool, error) {
+	for _, hasher := range h.hashers {
+		if hasher.Recognizes(hash) {
+			return hasher.Verify(password, hash)
+		}
+	}
+	return false, ErrUnknownHashFormat
+}
+
+func (h *chainHasher) NeedsRehash(hash string) bool {
+	return !h.preferred.Recognizes(hash) || h.preferred.NeedsRehash(hash)
+}
+
+func (h *chainHasher) Recognizes(hash string) bool {
+	for _, hasher := range h.hashers {
+		if hasher.Recognizes(hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package passwordPolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachedCorpus holds SHA-1 hashes of passwords known from breaches, in the format of
+// Have I Been Pwned. The path is either a single file of HASH:COUNT lines, which is
+// loaded into memory, or a directory of range files named by the first 5 hex characters
+// of the hash and holding SUFFIX:COUNT lines, which are read as needed.
+type BreachedCorpus struct {
+	dir    string
+	hashes map[string]struct{}
+}
+
+func LoadBreachedCorpus(path string) (*BreachedCorpus, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &BreachedCorpus{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		hashes[strings.ToUpper(hash)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &BreachedCorpus{hashes: hashes}, nil
+}
+
+func (b *BreachedCorpus) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if b.hashes != nil {
+		_, found := b.hashes[hash]
+		return found, nil
+	}
+
+	return b.rangeContains(hash[:5], hash[5:])
+}
+
+func (b *BreachedCorpus) rangeContains(prefix string, suffix string) (bool, error) {
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		// Range files may come without the extension as well
+		file, err = os.Open(filepath.Join(b.dir, prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
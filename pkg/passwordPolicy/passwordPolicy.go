@@ -0,0 +1,141 @@
+package passwordPolicy
+
+import (
+	"go-auth/config"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Shorter parts of an email are too common to forbid in a password
+const minPersonalLength = 3
+
+type (
+	Policy struct {
+		MinLength     int
+		MaxLength     int
+		RequireUpper  bool
+		RequireLower  bool
+		RequireDigit  bool
+		RequireSymbol bool
+		// HistorySize is how many of the latest passwords, the current one included, may
+		// not be used again
+		HistorySize int
+		breached    *BreachedCorpus
+	}
+
+	// Violation is a rule the password breaks, named like a validator tag so it can be
+	// reported the same way
+	Violation struct {
+		Tag   string
+		Param string
+	}
+)
+
+// NewPasswordPolicy builds the policy from the PASSWORD_POLICY_* settings and loads the
+// breached password corpus, if one is configured.
+func NewPasswordPolicy(cfg *config.Config) *Policy {
+	policy := &Policy{
+		MinLength:     int(cfg.PasswordPolicy.MinLength),
+		MaxLength:     int(cfg.PasswordPolicy.MaxLength),
+		RequireUpper:  cfg.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.PasswordPolicy.RequireSymbol,
+		HistorySize:   int(cfg.PasswordPolicy.HistorySize),
+	}
+
+	if cfg.PasswordPolicy.BreachedPasswordsPath != "" {
+		breached, err := LoadBreachedCorpus(cfg.PasswordPolicy.BreachedPasswordsPath)
+		if err != nil {
+			log.Fatalf("Error: Load breached passwords failed: %s", err.Error())
+		}
+		policy.breached = breached
+	}
+
+	return policy
+}
+
+// Check returns every rule the password breaks. Personal is what the password must not
+// contain, such as the email of the user.
+func (p *Policy) Check(password string, personal ...string) []Violation {
+	violations := make([]Violation, 0)
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{Tag: "min", Param: strconv.Itoa(p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{Tag: "max", Param: strconv.Itoa(p.MaxLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{Tag: "require_upper"})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{Tag: "require_lower"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{Tag: "require_digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{Tag: "require_symbol"})
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personalParts(personal) {
+		if strings.Contains(lowered, value) {
+			violations = append(violations, Violation{Tag: "personal_info"})
+			break
+		}
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.Contains(password)
+		if err != nil {
+			// The corpus is only a second line of defense, a read error does not block anybody
+			log.Printf("Error: Check breached password failed: %s", err.Error())
+		} else if breached {
+			violations = append(violations, Violation{Tag: "breached"})
+		}
+	}
+
+	return violations
+}
+
+// personalParts splits emails into their local part and domain name as well, so neither
+// can be used on its own.
+func personalParts(personal []string) []string {
+	parts := make([]string, 0)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		candidates := []string{value}
+		if local, domain, ok := strings.Cut(value, "@"); ok {
+			candidates = append(candidates, local, strings.Split(domain, ".")[0])
+		}
+
+		for _, candidate := range candidates {
+			if len(candidate) >= minPersonalLength {
+				parts = append(parts, candidate)
+			}
+		}
+	}
+	return parts
+}
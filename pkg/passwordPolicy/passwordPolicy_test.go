@@ -0,0 +1,96 @@
+package passwordPolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	strict := &Policy{
+		MinLength:     8,
+		MaxLength:     16,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   *Policy
+		password string
+		personal []string
+		want     []string
+	}{
+		{
+			name:     "meets every rule",
+			policy:   strict,
+			password: "Tr0ub4dor&3",
+			want:     []string{},
+		},
+		{
+			name:     "too short",
+			policy:   strict,
+			password: "Ab1!",
+			want:     []string{"min"},
+		},
+		{
+			name:     "too long",
+			policy:   strict,
+			password: "Tr0ub4dor&3Tr0ub4dor&3",
+			want:     []string{"max"},
+		},
+		{
+			name:     "length counts runes",
+			policy:   &Policy{MinLength: 4},
+			password: "ภาษา",
+			want:     []string{},
+		},
+		{
+			name:     "missing classes",
+			policy:   strict,
+			password: "lowercaseonly",
+			want:     []string{"require_upper", "require_digit", "require_symbol"},
+		},
+		{
+			name:     "contains the email",
+			policy:   strict,
+			password: "Jane.Doe@1x",
+			personal: []string{"jane.doe@example.com"},
+			want:     []string{"personal_info"},
+		},
+		{
+			name:     "contains the email domain",
+			policy:   strict,
+			password: "Example!2024",
+			personal: []string{"jane@example.com"},
+			want:     []string{"personal_info"},
+		},
+		{
+			name:     "short personal parts are ignored",
+			policy:   strict,
+			password: "Jo!Secret99",
+			personal: []string{"jo@ab.io"},
+			want:     []string{},
+		},
+		{
+			name:     "no maximum",
+			policy:   &Policy{MinLength: 1},
+			password: "a very long passphrase that goes on and on and on",
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, violation := range tt.policy.Check(tt.password, tt.personal...) {
+				got = append(got, violation.Tag)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package rateLimit
+
+import (
+	"errors"
+	"go-auth/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewFromConfig returns the limiter selected by RATE_LIMIT_DRIVER, Redis unless told
+// otherwise.
+func NewFromConfig(cfg *config.Config, redis *redis.Client) (Limiter, error) {
+	switch cfg.RateLimit.Driver {
+	case "", "redis":
+		return NewRedisLimiter(redis), nil
+	case "memory":
+		return NewMemoryLimiter(), nil
+	default:
+		return nil, errors.New("error: unknown rate limit driver " + cfg.RateLimit.Driver)
+	}
+}
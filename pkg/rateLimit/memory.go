@@ -0,0 +1,74 @@
+package rateLimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How many calls pass between sweeps of keys that went quiet
+const sweepInterval = 1000
+
+type memoryLimiter struct {
+	mu       sync.Mutex
+	requests map[string][]time.Time
+	windows  map[string]time.Duration
+	calls    int
+}
+
+// NewMemoryLimiter keeps the windows in this process. It is meant for tests and single
+// instance setups, every instance counts on its own.
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		requests: make(map[string][]time.Time),
+		windows:  make(map[string]time.Duration),
+	}
+}
+
+func (l *memoryLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	l.calls++
+	if l.calls%sweepInterval == 0 {
+		l.sweep(now)
+	}
+
+	requests := trim(l.requests[key], now.Add(-window))
+
+	allowed := int64(len(requests)) < limit
+	if allowed {
+		requests = append(requests, now)
+	}
+
+	l.requests[key] = requests
+	l.windows[key] = window
+
+	reset := window
+	if len(requests) > 0 {
+		reset = requests[0].Add(window).Sub(now)
+	}
+
+	return newResult(allowed, limit, int64(len(requests)), reset), nil
+}
+
+// trim drops the requests that happened before since. They are in order, so those are
+// at the front.
+func trim(requests []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(requests) && !requests[i].After(since) {
+		i++
+	}
+	return requests[i:]
+}
+
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, requests := range l.requests {
+		if len(trim(requests, now.Add(-l.windows[key]))) == 0 {
+			delete(l.requests, key)
+			delete(l.windows, key)
+		}
+	}
+}
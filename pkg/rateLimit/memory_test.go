@@ -0,0 +1,106 @@
+package rateLimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int64
+		window        time.Duration
+		calls         int
+		pause         time.Duration
+		after         int
+		wantAllowed   []bool
+		wantRemaining int64
+	}{
+		{
+			name:          "under the limit",
+			limit:         3,
+			window:        time.Minute,
+			calls:         2,
+			wantAllowed:   []bool{true, true},
+			wantRemaining: 1,
+		},
+		{
+			name:          "over the limit",
+			limit:         2,
+			window:        time.Minute,
+			calls:         4,
+			wantAllowed:   []bool{true, true, false, false},
+			wantRemaining: 0,
+		},
+		{
+			name:          "window slides",
+			limit:         1,
+			window:        50 * time.Millisecond,
+			calls:         2,
+			pause:         80 * time.Millisecond,
+			after:         1,
+			wantAllowed:   []bool{true, false, true},
+			wantRemaining: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewMemoryLimiter()
+			ctx := context.Background()
+
+			var got []bool
+			var last *Result
+			allow := func() {
+				result, err := l.Allow(ctx, "key", tt.limit, tt.window)
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+				got = append(got, result.Allowed)
+				last = result
+			}
+
+			for i := 0; i < tt.calls; i++ {
+				allow()
+			}
+			if tt.after > 0 {
+				time.Sleep(tt.pause)
+				for i := 0; i < tt.after; i++ {
+					allow()
+				}
+			}
+
+			if len(got) != len(tt.wantAllowed) {
+				t.Fatalf("Allow() gave %v, want %v", got, tt.wantAllowed)
+			}
+			for i := range got {
+				if got[i] != tt.wantAllowed[i] {
+					t.Fatalf("Allow() gave %v, want %v", got, tt.wantAllowed)
+				}
+			}
+
+			if last.Remaining != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", last.Remaining, tt.wantRemaining)
+			}
+			if last.Reset <= 0 || last.Reset > tt.window {
+				t.Errorf("Reset = %v, want within (0, %v]", last.Reset, tt.window)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterKeysAreSeparate(t *testing.T) {
+	l := NewMemoryLimiter()
+	ctx := context.Background()
+
+	for _, key := range []string{"ip:1.2.3.4", "ip:5.6.7.8"} {
+		result, err := l.Allow(ctx, key, 1, time.Minute)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed {
+			t.Errorf("Allow(%q) was turned away by the count of another key", key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package rateLimit
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Limiter counts requests per key over a sliding window. A request that is turned
+	// away does not count.
+	Limiter interface {
+		Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error)
+	}
+
+	Result struct {
+		Allowed   bool
+		Limit     int64
+		Remaining int64
+		// Reset is how long until the oldest counted request leaves the window, which is
+		// also when a turned away request may try again.
+		Reset time.Duration
+	}
+)
+
+func newResult(allowed bool, limit int64, count int64, reset time.Duration) *Result {
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
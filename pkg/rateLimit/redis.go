@@ -0,0 +1,61 @@
+package rateLimit
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The window of a key is a sorted set of its requests scored by time in milliseconds.
+// The script keeps trimming, counting and adding in one step, so concurrent requests of
+// several instances cannot slip past the limit together.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+
+local count = redis.call("ZCARD", KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+local reset = window
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, count, reset}
+`)
+
+type redisLimiter struct {
+	redis *redis.Client
+	// Requests in the same millisecond still need distinct members in the set
+	sequence atomic.Uint64
+}
+
+func NewRedisLimiter(redis *redis.Client) Limiter {
+	return &redisLimiter{redis: redis}
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (*Result, error) {
+	now := time.Now().UnixMilli()
+	member := strconv.FormatInt(now, 10) + "-" + strconv.FormatUint(l.sequence.Add(1), 10)
+
+	values, err := slidingWindowScript.Run(ctx, l.redis, []string{"rate_limit:" + key},
+		now, window.Milliseconds(), limit, member).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return newResult(values[0] == 1, limit, values[1], time.Duration(values[2])*time.Millisecond), nil
+}
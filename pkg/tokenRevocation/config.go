@@ -0,0 +1,25 @@
+package tokenRevocation
+
+import (
+	"errors"
+	"go-auth/config"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewFromConfig(cfg *config.Config, db *mongo.Client, redis *redis.Client) (*Store, error) {
+	if cfg.Jwt.RevocationFilterRefreshInterval <= 0 {
+		return nil, errors.New("error: REVOCATION_FILTER_REFRESH_INTERVAL must be positive")
+	}
+	if cfg.Jwt.RevocationFilterCapacity <= 0 {
+		return nil, errors.New("error: REVOCATION_FILTER_CAPACITY must be positive")
+	}
+
+	return New(NewMongoBackend(db, redis), Options{
+		FilterCapacity:      uint(cfg.Jwt.RevocationFilterCapacity),
+		RefreshInterval:     time.Duration(cfg.Jwt.RevocationFilterRefreshInterval) * time.Second,
+		AccessTokenLifetime: time.Duration(cfg.Jwt.AccessTokenDuration) * time.Minute,
+	}), nil
+}
@@ -0,0 +1,222 @@
+package tokenRevocation
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Every instance learns about new revocations on this channel
+const revokedChannel = "revoked_jti"
+
+// How long a token version stays cached. Bumps write through, so this only bounds memory.
+const tokenVersionTTL = time.Hour
+
+type (
+	mongoBackend struct {
+		db    *mongo.Client
+		redis *redis.Client
+	}
+
+	redisSubscription struct {
+		pubsub *redis.PubSub
+		jtis   chan string
+		closed chan struct{}
+	}
+)
+
+// NewMongoBackend keeps revoked jtis in Auth.Blacklists and token versions on the users,
+// with Redis caching both and passing new revocations on to the other instances.
+func NewMongoBackend(db *mongo.Client, redis *redis.Client) Backend {
+	return &mongoBackend{db: db, redis: redis}
+}
+
+func (b *mongoBackend) blacklistCollection() *mongo.Collection {
+	return b.db.Database("Auth").Collection("Blacklists")
+}
+
+func (b *mongoBackend) userCollection() *mongo.Collection {
+	return b.db.Database("Auth").Collection("Users")
+}
+
+func revokedKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func tokenVersionKey(userId string) string {
+	return "token_version:" + userId
+}
+
+func (b *mongoBackend) EachRevoked(fn func(jti string)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	filter := bson.M{"expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetProjection(bson.M{"jti": 1, "_id": 0})
+
+	cursor, err := b.blacklistCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var revoked struct {
+			Jti string `bson:"jti"`
+		}
+		if err := cursor.Decode(&revoked); err != nil {
+			return err
+		}
+
+		fn(revoked.Jti)
+	}
+
+	return cursor.Err()
+}
+
+func (b *mongoBackend) Revoke(jti string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"jti": jti}
+	update := bson.M{"$setOnInsert": bson.M{
+		"jti":        jti,
+		"expires_at": expiresAt,
+	}}
+
+	if _, err := b.blacklistCollection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return err
+	}
+
+	return b.redis.Set(ctx, revokedKey(jti), 1, time.Until(expiresAt)).Err()
+}
+
+func (b *mongoBackend) Publish(jti string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return b.redis.Publish(ctx, revokedChannel, jti).Err()
+}
+
+func (b *mongoBackend) Subscribe() Subscription {
+	subscription := &redisSubscription{
+		pubsub: b.redis.Subscribe(context.Background(), revokedChannel),
+		jtis:   make(chan string),
+		closed: make(chan struct{}),
+	}
+
+	go func() {
+		for message := range subscription.pubsub.Channel() {
+			select {
+			case subscription.jtis <- message.Payload:
+			case <-subscription.closed:
+				return
+			}
+		}
+	}()
+
+	return subscription
+}
+
+func (s *redisSubscription) Revoked() <-chan string {
+	return s.jtis
+}
+
+func (s *redisSubscription) Close() error {
+	close(s.closed)
+	return s.pubsub.Close()
+}
+
+// IsRevoked asks Redis first and then Mongo, in case Redis lost the key
+func (b *mongoBackend) IsRevoked(jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	n, err := b.redis.Exists(ctx, revokedKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+
+	filter := bson.M{
+		"jti":        jti,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	count, err := b.blacklistCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (b *mongoBackend) BumpTokenVersion(userId string) (int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": objectID}
+	update := bson.M{"$inc": bson.M{"token_version": 1}}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"token_version": 1})
+
+	var user struct {
+		TokenVersion int64 `bson:"token_version"`
+	}
+	if err := b.userCollection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&user); err != nil {
+		return 0, err
+	}
+
+	// Overwrite whatever a concurrent reader may have cached
+	if err := b.redis.Set(ctx, tokenVersionKey(userId), user.TokenVersion, tokenVersionTTL).Err(); err != nil {
+		return 0, err
+	}
+
+	return user.TokenVersion, nil
+}
+
+// TokenVersion returns the cached version of the user, loading it from Mongo on a miss.
+// SetNX keeps a newer version written by a bump in the meantime.
+func (b *mongoBackend) TokenVersion(userId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cached, err := b.redis.Get(ctx, tokenVersionKey(userId)).Result()
+	if err == nil {
+		return strconv.ParseInt(cached, 10, 64)
+	} else if err != redis.Nil {
+		return 0, err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	opts := options.FindOne().SetProjection(bson.M{"token_version": 1})
+
+	var user struct {
+		TokenVersion int64 `bson:"token_version"`
+	}
+	if err := b.userCollection().FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&user); err != nil {
+		return 0, err
+	}
+
+	b.redis.SetNX(ctx, tokenVersionKey(userId), user.TokenVersion, tokenVersionTTL)
+
+	return user.TokenVersion, nil
+}
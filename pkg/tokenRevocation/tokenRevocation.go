@@ -0,0 +1,249 @@
+package tokenRevocation
+
+import (
+	"go-auth/pkg/jwtAuth"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// Chance that the filter sends an unrevoked jti on to the backend
+const filterFalsePositiveRate = 0.001
+
+type (
+	// Backend keeps the revocations and token versions. The Store only asks it about the
+	// jtis its filter has seen, and about token versions.
+	Backend interface {
+		// EachRevoked calls fn with every jti that is revoked and not yet expired
+		EachRevoked(fn func(jti string)) error
+		// Revoke keeps the jti revoked until expiresAt
+		Revoke(jti string, expiresAt time.Time) error
+		// Publish tells the subscriptions of every instance about a revoked jti
+		Publish(jti string) error
+		Subscribe() Subscription
+		IsRevoked(jti string) (bool, error)
+		BumpTokenVersion(userId string) (int64, error)
+		TokenVersion(userId string) (int64, error)
+	}
+
+	// Subscription delivers the jtis published by any instance until it is closed
+	Subscription interface {
+		Revoked() <-chan string
+		Close() error
+	}
+
+	Options struct {
+		// FilterCapacity is the number of live revocations the filter is sized for. Beyond
+		// it the filter still works, it just lets more lookups through to the backend.
+		FilterCapacity uint
+		// RefreshInterval rebuilds the filter from the backend, dropping expired jtis and
+		// picking up any message the subscription missed.
+		RefreshInterval time.Duration
+		// AccessTokenLifetime is how long a revoked session is kept, the longest any of its
+		// access tokens can live
+		AccessTokenLifetime time.Duration
+	}
+
+	// Store revokes tokens by jti until they expire, the access tokens of a session by its
+	// family id, and all tokens of a user at once by bumping the token version of the user.
+	//
+	// The backend keeps the revoked jtis, but is not asked for the common case of a token
+	// that was never revoked. An in-process Bloom filter of all live jtis answers that
+	// one, and a jti only goes on to the backend when the filter has seen it.
+	Store struct {
+		backend Backend
+		opts    Options
+
+		mu       sync.RWMutex
+		filter   *bloom.BloomFilter
+		building *bloom.BloomFilter
+
+		stop chan struct{}
+		done chan struct{}
+	}
+)
+
+func New(backend Backend, opts Options) *Store {
+	return &Store{
+		backend: backend,
+		opts:    opts,
+		filter:  bloom.NewWithEstimates(opts.FilterCapacity, filterFalsePositiveRate),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start loads the filter and keeps it in sync until Stop. The subscription comes first,
+// so nothing revoked during the load is missed.
+func (s *Store) Start() {
+	subscription := s.backend.Subscribe()
+
+	if err := s.Refresh(); err != nil {
+		log.Printf("Error: Load revoked tokens failed: %s", err.Error())
+	}
+
+	go func() {
+		defer close(s.done)
+		defer subscription.Close()
+
+		ticker := time.NewTicker(s.opts.RefreshInterval)
+		defer ticker.Stop()
+
+		revoked := subscription.Revoked()
+		for {
+			select {
+			case jti := <-revoked:
+				s.remember(jti)
+			case <-ticker.C:
+				if err := s.Refresh(); err != nil {
+					log.Printf("Error: Refresh revoked tokens failed: %s", err.Error())
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Store) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// remember adds the jti to the filter, and to the one being built if a refresh runs.
+func (s *Store) remember(jti string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filter.AddString(jti)
+	if s.building != nil {
+		s.building.AddString(jti)
+	}
+}
+
+func (s *Store) mayBeRevoked(jti string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.filter.TestString(jti)
+}
+
+// Refresh rebuilds the filter from the live revocations of the backend. A Bloom filter
+// cannot forget, so this is also what drops the expired ones.
+func (s *Store) Refresh() error {
+	building := bloom.NewWithEstimates(s.opts.FilterCapacity, filterFalsePositiveRate)
+
+	s.mu.Lock()
+	s.building = building
+	s.mu.Unlock()
+
+	// Lock per jti, so requests are not held up for the whole load
+	err := s.backend.EachRevoked(func(jti string) {
+		s.mu.Lock()
+		building.AddString(jti)
+		s.mu.Unlock()
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.building = nil
+	if err != nil {
+		return err
+	}
+	s.filter = building
+
+	return nil
+}
+
+// Revoke blocks the jti until expiresAt and tells every instance about it.
+func (s *Store) Revoke(jti string, expiresAt time.Time) error {
+	if jti == "" || time.Until(expiresAt) <= 0 {
+		return nil
+	}
+
+	if err := s.backend.Revoke(jti, expiresAt); err != nil {
+		return err
+	}
+
+	s.remember(jti)
+
+	// A missed message is picked up by the next refresh of the other instances
+	if err := s.backend.Publish(jti); err != nil {
+		log.Printf("Error: Publish revoked token failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// familyKey keeps the ids of revoked sessions apart from jtis in the same store
+func familyKey(familyId string) string {
+	return "fid:" + familyId
+}
+
+// RevokeFamily blocks every access token of the session, the token family, until the
+// last one of them has expired.
+func (s *Store) RevokeFamily(familyId string) error {
+	if familyId == "" {
+		return nil
+	}
+
+	return s.Revoke(familyKey(familyId), time.Now().Add(s.opts.AccessTokenLifetime))
+}
+
+// IsBlacklisted reports whether the jti was revoked. Only jtis the filter has seen cost a
+// lookup in the backend.
+func (s *Store) IsBlacklisted(jti string) (bool, error) {
+	if jti == "" || !s.mayBeRevoked(jti) {
+		return false, nil
+	}
+
+	return s.backend.IsRevoked(jti)
+}
+
+// BumpTokenVersion invalidates every token issued to the user so far. Returns the new
+// version, which tokens issued from now on must carry.
+func (s *Store) BumpTokenVersion(userId string) (int64, error) {
+	return s.backend.BumpTokenVersion(userId)
+}
+
+// IsRevoked checks the jti, the session and the token version of the user, which is one
+// cache hit for a token that was never revoked. It fails open when the stores are down,
+// an outage must not sign everybody out.
+func (s *Store) IsRevoked(claims *jwtAuth.AuthMapClaims) bool {
+	blacklisted, err := s.IsBlacklisted(claims.ID)
+	if err != nil {
+		log.Printf("Error: Check revoked token failed: %s", err.Error())
+	}
+	if blacklisted {
+		return true
+	}
+
+	if claims.Claims == nil {
+		return false
+	}
+
+	if claims.FamilyId != "" {
+		blacklisted, err := s.IsBlacklisted(familyKey(claims.FamilyId))
+		if err != nil {
+			log.Printf("Error: Check revoked session failed: %s", err.Error())
+		}
+		if blacklisted {
+			return true
+		}
+	}
+
+	if claims.UserId == "" {
+		return false
+	}
+
+	version, err := s.backend.TokenVersion(claims.UserId)
+	if err != nil {
+		log.Printf("Error: Check token version failed: %s", err.Error())
+		return false
+	}
+
+	return claims.TokenVersion < version
+}
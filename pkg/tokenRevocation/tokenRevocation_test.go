@@ -0,0 +1,330 @@
+package tokenRevocation
+
+import (
+	"errors"
+	"go-auth/pkg/jwtAuth"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend keeps revocations in memory and counts the lookups that got past the filter
+type fakeBackend struct {
+	mu       sync.Mutex
+	revoked  map[string]time.Time
+	versions map[string]int64
+	lookups  int
+	err      error
+
+	published    []string
+	subscription *fakeSubscription
+}
+
+type fakeSubscription struct {
+	jtis chan string
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		revoked:      make(map[string]time.Time),
+		versions:     make(map[string]int64),
+		subscription: &fakeSubscription{jtis: make(chan string)},
+	}
+}
+
+func (b *fakeBackend) EachRevoked(fn func(jti string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for jti, expiresAt := range b.revoked {
+		if time.Now().Before(expiresAt) {
+			fn(jti)
+		}
+	}
+	return b.err
+}
+
+func (b *fakeBackend) Revoke(jti string, expiresAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.revoked[jti] = expiresAt
+	return b.err
+}
+
+func (b *fakeBackend) Publish(jti string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published = append(b.published, jti)
+	return nil
+}
+
+func (b *fakeBackend) Subscribe() Subscription {
+	return b.subscription
+}
+
+func (b *fakeBackend) IsRevoked(jti string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lookups++
+	if b.err != nil {
+		return false, b.err
+	}
+	expiresAt, ok := b.revoked[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+func (b *fakeBackend) BumpTokenVersion(userId string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.versions[userId]++
+	return b.versions[userId], nil
+}
+
+func (b *fakeBackend) TokenVersion(userId string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.versions[userId], b.err
+}
+
+func (s *fakeSubscription) Revoked() <-chan string {
+	return s.jtis
+}
+
+func (s *fakeSubscription) Close() error {
+	return nil
+}
+
+func newTestStore(backend Backend) *Store {
+	return New(backend, Options{FilterCapacity: 1000, RefreshInterval: time.Hour})
+}
+
+func TestStoreRefresh(t *testing.T) {
+	backend := newFakeBackend()
+	backend.revoked["live"] = time.Now().Add(time.Hour)
+	backend.revoked["expired"] = time.Now().Add(-time.Hour)
+	s := newTestStore(backend)
+
+	if blacklisted, _ := s.IsBlacklisted("live"); blacklisted {
+		t.Fatalf("IsBlacklisted() = true before the filter was loaded")
+	}
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		jti         string
+		want        bool
+		wantLookups int
+	}{
+		{name: "revoked", jti: "live", want: true, wantLookups: 1},
+		{name: "expired", jti: "expired", want: false, wantLookups: 0},
+		{name: "never revoked", jti: "other", want: false, wantLookups: 0},
+		{name: "empty", jti: "", want: false, wantLookups: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.lookups = 0
+
+			got, err := s.IsBlacklisted(tt.jti)
+			if err != nil {
+				t.Fatalf("IsBlacklisted() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBlacklisted() = %v, want %v", got, tt.want)
+			}
+			if backend.lookups != tt.wantLookups {
+				t.Errorf("backend lookups = %d, want %d", backend.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestStoreRefreshDropsExpired(t *testing.T) {
+	backend := newFakeBackend()
+	backend.revoked["jti"] = time.Now().Add(time.Hour)
+	s := newTestStore(backend)
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	delete(backend.revoked, "jti")
+
+	// The filter still has it, so the backend gives the exact answer
+	backend.lookups = 0
+	if blacklisted, _ := s.IsBlacklisted("jti"); blacklisted || backend.lookups != 1 {
+		t.Errorf("IsBlacklisted() = %v after %d lookups, want false after 1", blacklisted, backend.lookups)
+	}
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	backend.lookups = 0
+	if blacklisted, _ := s.IsBlacklisted("jti"); blacklisted || backend.lookups != 0 {
+		t.Errorf("IsBlacklisted() = %v after %d lookups, want false without a lookup", blacklisted, backend.lookups)
+	}
+}
+
+func TestStoreRefreshFailureKeepsFilter(t *testing.T) {
+	backend := newFakeBackend()
+	backend.revoked["jti"] = time.Now().Add(time.Hour)
+	s := newTestStore(backend)
+
+	if err := s.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	backend.err = errors.New("down")
+	if err := s.Refresh(); err == nil {
+		t.Fatalf("Refresh() error = nil, want the backend error")
+	}
+
+	if !s.mayBeRevoked("jti") {
+		t.Errorf("a failed refresh dropped the loaded filter")
+	}
+}
+
+func TestStoreRevoke(t *testing.T) {
+	tests := []struct {
+		name          string
+		jti           string
+		expiresAt     time.Time
+		wantRevoked   bool
+		wantPublished int
+	}{
+		{name: "live token", jti: "jti", expiresAt: time.Now().Add(time.Hour), wantRevoked: true, wantPublished: 1},
+		{name: "expired token", jti: "jti", expiresAt: time.Now().Add(-time.Minute)},
+		{name: "no jti", jti: "", expiresAt: time.Now().Add(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			s := newTestStore(backend)
+
+			if err := s.Revoke(tt.jti, tt.expiresAt); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+
+			if got, _ := s.IsBlacklisted(tt.jti); got != tt.wantRevoked {
+				t.Errorf("IsBlacklisted() = %v, want %v", got, tt.wantRevoked)
+			}
+			if len(backend.published) != tt.wantPublished {
+				t.Errorf("published = %v, want %d", backend.published, tt.wantPublished)
+			}
+		})
+	}
+}
+
+func TestStoreSubscription(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestStore(backend)
+	s.Start()
+	defer s.Stop()
+
+	// Another instance revoked the jti after this one loaded the filter
+	backend.Revoke("jti", time.Now().Add(time.Hour))
+	backend.subscription.jtis <- "jti"
+
+	deadline := time.Now().Add(time.Second)
+	for !s.mayBeRevoked("jti") {
+		if time.Now().After(deadline) {
+			t.Fatalf("the published jti never reached the filter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if blacklisted, _ := s.IsBlacklisted("jti"); !blacklisted {
+		t.Errorf("IsBlacklisted() = false, want true")
+	}
+}
+
+func TestStoreIsRevoked(t *testing.T) {
+	const userId = "user-1"
+
+	tests := []struct {
+		name    string
+		claims  *jwtAuth.AuthMapClaims
+		version int64
+		err     error
+		want    bool
+	}{
+		{name: "current version", claims: newClaims("jti", userId, 2), version: 2, want: false},
+		{name: "older version", claims: newClaims("jti", userId, 1), version: 2, want: true},
+		{name: "revoked jti", claims: newClaims("revoked", userId, 2), version: 2, want: true},
+		{name: "no user", claims: newClaims("jti", "", 0), version: 2, want: false},
+		{name: "backend down", claims: newClaims("jti", userId, 1), version: 2, err: errors.New("down"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			backend.versions[userId] = tt.version
+			s := newTestStore(backend)
+
+			if err := s.Revoke("revoked", time.Now().Add(time.Hour)); err != nil {
+				t.Fatalf("Revoke() error = %v", err)
+			}
+			backend.err = tt.err
+
+			if got := s.IsRevoked(tt.claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreRevokeFamily(t *testing.T) {
+	backend := newFakeBackend()
+	s := New(backend, Options{FilterCapacity: 1000, RefreshInterval: time.Hour, AccessTokenLifetime: time.Hour})
+
+	if err := s.RevokeFamily("lost-phone"); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		familyId string
+		want     bool
+	}{
+		{name: "revoked session", familyId: "lost-phone", want: true},
+		{name: "other session", familyId: "laptop", want: false},
+		{name: "no session", familyId: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := newClaims("jti", "user-1", 0)
+			claims.FamilyId = tt.familyId
+
+			if got := s.IsRevoked(claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A jti that happens to equal the family id is not revoked with it
+	if blacklisted, _ := s.IsBlacklisted("lost-phone"); blacklisted {
+		t.Errorf("IsBlacklisted() = true for the bare family id")
+	}
+
+	if expiresAt := backend.revoked[familyKey("lost-phone")]; time.Until(expiresAt) > time.Hour {
+		t.Errorf("session revoked until %v, want at most an access token lifetime", expiresAt)
+	}
+}
+
+func newClaims(jti string, userId string, tokenVersion int64) *jwtAuth.AuthMapClaims {
+	claims := &jwtAuth.AuthMapClaims{
+		Claims: &jwtAuth.Claims{UserId: userId, TokenVersion: tokenVersion},
+	}
+	claims.ID = jti
+	return claims
+}
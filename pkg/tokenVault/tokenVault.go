@@ -0,0 +1,144 @@
+package tokenVault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-auth/config"
+	"log"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("error: sealed with an unknown key")
+
+var ErrInvalidSealed = errors.New("error: sealed value is invalid")
+
+// KeyRing encrypts with AES-GCM under the active key and decrypts under any key of the
+// ring. A sealed value is the id of its key and the base64 of nonce and ciphertext, so
+// values of a retired key can still be read and sealed again under the new one.
+type KeyRing struct {
+	activeId string
+	aeads    map[string]cipher.AEAD
+}
+
+func NewKeyRing(activeId string, keys map[string][]byte) (*KeyRing, error) {
+	ring := &KeyRing{
+		activeId: activeId,
+		aeads:    make(map[string]cipher.AEAD),
+	}
+
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("error: key id %q is invalid", id)
+		}
+
+		// AES-256 only, a shorter key is more likely a mistake than a choice
+		if len(key) != 32 {
+			return nil, fmt.Errorf("error: key %q is %d bytes, it has to be 32", id, len(key))
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("error: key %q: %w", id, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		ring.aeads[id] = aead
+	}
+
+	if _, ok := ring.aeads[activeId]; !ok {
+		return nil, fmt.Errorf("error: active key %q is not in the key ring", activeId)
+	}
+
+	return ring, nil
+}
+
+// NewTokenVault builds the key ring of TOKEN_VAULT_KEYS. The active key defaults to the
+// first one. Without keys it returns nil and provider tokens are not stored at all, a
+// made up key would lose them on every restart.
+func NewTokenVault(cfg *config.Config) *KeyRing {
+	keys := make(map[string][]byte)
+	activeId := cfg.TokenVault.ActiveKeyId
+
+	for _, pair := range cfg.TokenVault.Keys {
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			log.Fatalf("Error: Token vault key %q is not an id:key pair", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			log.Fatalf("Error: Decode token vault key %q failed: %s", id, err.Error())
+		}
+
+		keys[id] = key
+		if activeId == "" {
+			activeId = id
+		}
+	}
+
+	if len(keys) == 0 {
+		log.Printf("Warning: TOKEN_VAULT_KEYS is empty, provider tokens are not stored")
+		return nil
+	}
+
+	ring, err := NewKeyRing(activeId, keys)
+	if err != nil {
+		log.Fatalf("Error: Load token vault keys failed: %s", err.Error())
+	}
+
+	return ring
+}
+
+// Seal encrypts the plaintext under the active key. The additional data is not stored,
+// it has to be given again to open the value, which keeps a value from being moved to
+// another record.
+func (r *KeyRing) Seal(plaintext []byte, additionalData []byte) (string, error) {
+	aead := r.aeads[r.activeId]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData)
+
+	return r.activeId + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (r *KeyRing) Open(sealed string, additionalData []byte) ([]byte, error) {
+	id, encoded, ok := strings.Cut(sealed, ":")
+	if !ok {
+		return nil, ErrInvalidSealed
+	}
+
+	aead, ok := r.aeads[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, ErrInvalidSealed
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrInvalidSealed
+	}
+
+	return plaintext, nil
+}
+
+// NeedsReseal reports whether the value was sealed under a key other than the active one
+func (r *KeyRing) NeedsReseal(sealed string) bool {
+	id, _, _ := strings.Cut(sealed, ":")
+	return id != r.activeId
+}
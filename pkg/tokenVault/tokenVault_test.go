@@ -0,0 +1,101 @@
+package tokenVault
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func mustKeyRing(t *testing.T, activeId string, keys map[string][]byte) *KeyRing {
+	t.Helper()
+
+	ring, err := NewKeyRing(activeId, keys)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+	return ring
+}
+
+func TestNewKeyRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		activeId string
+		keys     map[string][]byte
+		wantErr  bool
+	}{
+		{name: "valid", activeId: "k1", keys: map[string][]byte{"k1": oldKey}},
+		{name: "no AES key size", activeId: "k1", keys: map[string][]byte{"k1": oldKey[:15]}, wantErr: true},
+		{name: "AES-128 key", activeId: "k1", keys: map[string][]byte{"k1": oldKey[:16]}, wantErr: true},
+		{name: "unknown active key", activeId: "k2", keys: map[string][]byte{"k1": oldKey}, wantErr: true},
+		{name: "colon in id", activeId: "k:1", keys: map[string][]byte{"k:1": oldKey}, wantErr: true},
+		{name: "empty id", activeId: "", keys: map[string][]byte{"": oldKey}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyRing(tt.activeId, tt.keys)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeyRing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	before := mustKeyRing(t, "k1", map[string][]byte{"k1": oldKey})
+	rotated := mustKeyRing(t, "k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+	dropped := mustKeyRing(t, "k2", map[string][]byte{"k2": newKey})
+
+	plaintext := []byte("provider-access-token")
+	additionalData := []byte("user:google")
+
+	sealedOld, err := before.Seal(plaintext, additionalData)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	sealedNew, err := rotated.Seal(plaintext, additionalData)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if !strings.HasPrefix(sealedNew, "k2:") {
+		t.Fatalf("Seal() = %q, want it sealed under k2", sealedNew)
+	}
+
+	tests := []struct {
+		name           string
+		ring           *KeyRing
+		sealed         string
+		additionalData []byte
+		wantErr        error
+		wantReseal     bool
+	}{
+		{name: "old value after rotation", ring: rotated, sealed: sealedOld, additionalData: additionalData, wantReseal: true},
+		{name: "new value after rotation", ring: rotated, sealed: sealedNew, additionalData: additionalData},
+		{name: "old value once its key is dropped", ring: dropped, sealed: sealedOld, additionalData: additionalData, wantErr: ErrUnknownKey, wantReseal: true},
+		{name: "other additional data", ring: rotated, sealed: sealedNew, additionalData: []byte("user:github"), wantErr: ErrInvalidSealed},
+		{name: "tampered value", ring: rotated, sealed: sealedNew[:len(sealedNew)-2] + "AA", additionalData: additionalData, wantErr: ErrInvalidSealed},
+		{name: "no key id", ring: rotated, sealed: "garbage", additionalData: additionalData, wantErr: ErrInvalidSealed, wantReseal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ring.Open(tt.sealed, tt.additionalData)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, plaintext) {
+				t.Errorf("Open() = %q, want %q", got, plaintext)
+			}
+			if reseal := tt.ring.NeedsReseal(tt.sealed); reseal != tt.wantReseal {
+				t.Errorf("NeedsReseal() = %v, want %v", reseal, tt.wantReseal)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA1 secret of RFC 6238 appendix B, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, cut to the last six digits like a six digit code is
+	tests := []struct {
+		name string
+		time int64
+		want string
+	}{
+		{name: "59", time: 59, want: "287082"},
+		{name: "1111111109", time: 1111111109, want: "081804"},
+		{name: "1111111111", time: 1111111111, want: "050471"},
+		{name: "1234567890", time: 1234567890, want: "005924"},
+		{name: "2000000000", time: 2000000000, want: "279037"},
+		{name: "20000000000", time: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.time, 0)))
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Code() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Errorf("Code() error = nil, want a decode error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	code := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOk   bool
+	}{
+		{name: "rfc vector", secret: rfcSecret, code: "050471", wantStep: step, wantOk: true},
+		{name: "previous step", secret: rfcSecret, code: code(step - 1), wantStep: step - 1, wantOk: true},
+		{name: "next step", secret: rfcSecret, code: code(step + 1), wantStep: step + 1, wantOk: true},
+		{name: "outside the skew before", secret: rfcSecret, code: code(step - 2)},
+		{name: "outside the skew after", secret: rfcSecret, code: code(step + 2)},
+		{name: "surrounding spaces", secret: rfcSecret, code: " 050471 ", wantStep: step, wantOk: true},
+		{name: "lower case secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "050471", wantStep: step, wantOk: true},
+		{name: "eight digits", secret: rfcSecret, code: "14050471"},
+		{name: "wrong code", secret: rfcSecret, code: "000000"},
+		{name: "invalid secret", secret: "not base32!", code: "050471"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOk := Validate(tt.secret, tt.code, now)
+			if gotOk != tt.wantOk {
+				t.Fatalf("Validate() ok = %v, want %v", gotOk, tt.wantOk)
+			}
+			if gotStep != tt.wantStep {
+				t.Errorf("Validate() step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
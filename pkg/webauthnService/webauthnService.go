@@ -0,0 +1,21 @@
+package webauthnService
+
+import (
+	"go-auth/config"
+	"log"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+func NewWebAuthn(cfg *config.Config) *webauthn.WebAuthn {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     cfg.WebAuthn.RPOrigins,
+	})
+	if err != nil {
+		log.Fatalf("Error: Create WebAuthn config failed: %s", err.Error())
+	}
+
+	return webAuthn
+}
@@ -5,7 +5,14 @@ import (
 	"go-auth/config"
 	auth "go-auth/modules/auth/route"
 	user "go-auth/modules/user/route"
+	"go-auth/pkg/jwtAuth"
+	"go-auth/pkg/keyStore"
+	"go-auth/pkg/rateLimit"
+	"go-auth/pkg/redisService"
+	"go-auth/pkg/tokenRevocation"
 	"go-auth/server/types"
+	"log"
+	"net"
 
 	"sync"
 
@@ -20,17 +27,55 @@ var (
 )
 
 func Start(ctx context.Context, cfg *config.Config, db *mongo.Client) {
+	if cfg.Jwt.KeyStoreDriver != "" {
+		rotatingKeyStore, err := keyStore.NewFromConfig(cfg, db)
+		if err != nil {
+			log.Fatalf("Error: Load JWT signing keys failed: %s", err.Error())
+		}
+		rotatingKeyStore.Start()
+		defer rotatingKeyStore.Stop()
+		jwtAuth.SetKeyStore(rotatingKeyStore)
+	} else {
+		staticKeyStore, err := jwtAuth.LoadKeyStore(cfg.Jwt.SigningAlg, cfg.Jwt.PrivateKeyPath, cfg.Jwt.KeyId)
+		if err != nil {
+			log.Fatalf("Error: Load JWT signing key failed: %s", err.Error())
+		}
+		jwtAuth.SetKeyStore(staticKeyStore)
+	}
+
+	redisClient := redisService.NewRedis(cfg)
+
+	revocation, err := tokenRevocation.NewFromConfig(cfg, db, redisClient)
+	if err != nil {
+		log.Fatalf("Error: Load token revocation failed: %s", err.Error())
+	}
+	revocation.Start()
+	defer revocation.Stop()
+	jwtAuth.SetRevocationList(revocation)
+
+	rateLimiter, err := rateLimit.NewFromConfig(cfg, redisClient)
+	if err != nil {
+		log.Fatalf("Error: Create rate limiter failed: %s", err.Error())
+	}
+
 	s := &types.Server{
-		App: echo.New(),
-		Db:  db,
-		Cfg: cfg,
+		App:         echo.New(),
+		Db:          db,
+		Redis:       redisClient,
+		Cfg:         cfg,
+		Revocation:  revocation,
+		RateLimiter: rateLimiter,
 	}
 
+	s.App.IPExtractor = ipExtractor(cfg)
+
 	// CORS
 	s.App.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		Skipper:      middleware.DefaultSkipper,
 		AllowOrigins: []string{"*"},
 		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE},
+		// Let browser clients see how much of their rate limit is left
+		ExposeHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 	}))
 
 	// s.App.Use(middleware.Recover())
@@ -39,3 +84,26 @@ func Start(ctx context.Context, cfg *config.Config, db *mongo.Client) {
 	user.UserRoute(s)
 	s.App.Logger.Fatal(s.App.Start(":8080"))
 }
+
+// ipExtractor decides where RealIP comes from. Login throttling and rate limits count per
+// address, so X-Forwarded-For is only believed when the peer is one of our proxies.
+func ipExtractor(cfg *config.Config) echo.IPExtractor {
+	if len(cfg.Server.TrustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Fatalf("Error: Parse trusted proxy %s failed: %s", proxy, err.Error())
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -2,15 +2,23 @@ package types
 
 import (
 	"go-auth/config"
+	"go-auth/pkg/rateLimit"
+	"go-auth/pkg/tokenRevocation"
 
 	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type (
 	Server struct {
-		App *echo.Echo
-		Db  *mongo.Client
-		Cfg *config.Config
+		App   *echo.Echo
+		Db    *mongo.Client
+		Redis *redis.Client
+		Cfg   *config.Config
+		// Revocation is shared by every module so they all see the same revoked tokens
+		Revocation *tokenRevocation.Store
+		// RateLimiter is shared so routes of every module count against the same store
+		RateLimiter rateLimit.Limiter
 	}
 )
@@ -3,6 +3,7 @@ package utils
 import (
 	"log"
 	"strconv"
+	"strings"
 )
 
 func ParseStringToInt(s string) int64 {
@@ -12,3 +13,22 @@ func ParseStringToInt(s string) int64 {
 	}
 	return result
 }
+
+// ParseStringToIntOrDefault is ParseStringToInt for optional settings, an empty string gives def
+func ParseStringToIntOrDefault(s string, def int64) int64 {
+	if s == "" {
+		return def
+	}
+	return ParseStringToInt(s)
+}
+
+// ParseStringToSlice splits a comma separated setting, ignoring empty items
+func ParseStringToSlice(s string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
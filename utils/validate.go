@@ -1,6 +1,10 @@
 package utils
 
-import "github.com/go-playground/validator/v10"
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
 
 type (
 	validationErrorResponse struct {
@@ -8,11 +12,41 @@ type (
 		Tag   string `json:"tag"`
 		Value string `json:"value"`
 	}
+
+	// FieldError is a validation failure found outside the validator, such as a password
+	// that breaks the password policy. It is reported just like a failed validate tag.
+	FieldError struct {
+		Field string
+		Tag   string
+		Param string
+	}
+
+	FieldErrors []FieldError
 )
 
+func (e FieldErrors) Error() string {
+	tags := make([]string, 0, len(e))
+	for _, err := range e {
+		tags = append(tags, err.Field+": "+err.Tag)
+	}
+	return "validation failed: " + strings.Join(tags, ", ")
+}
+
 func FormatValidationError(err error) []validationErrorResponse {
 	var errors []validationErrorResponse
 
+	if fieldErrors, ok := err.(FieldErrors); ok {
+		for _, err := range fieldErrors {
+			errors = append(errors, validationErrorResponse{
+				Field: err.Field,
+				Tag:   err.Tag,
+				Value: err.Param,
+			})
+		}
+
+		return errors
+	}
+
 	for _, err := range err.(validator.ValidationErrors) {
 		element := &validationErrorResponse{
 			Field: err.StructNamespace(),